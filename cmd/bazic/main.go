@@ -1,8 +1,8 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 
@@ -10,26 +10,57 @@ import (
 	"github.com/cedricmar/bazic/pkg/scanner"
 )
 
+// slashComments keeps scripts written before "//" meant floor division running
+var slashComments = flag.Bool("slash-comments", false, "scan // as a comment instead of floor division")
+
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Println("Usage: bazic [script]")
+	flag.Parse()
+
+	if flag.NArg() == 2 && flag.Arg(0) == "check" {
+		CheckFile(flag.Arg(1))
+	} else if flag.NArg() > 1 {
+		fmt.Println("Usage: bazic [-slash-comments] [[check] script]")
 		os.Exit(64)
-	} else if len(os.Args) == 2 {
-		RunFile(os.Args[1])
+	} else if flag.NArg() == 1 {
+		RunFile(flag.Arg(0))
 	} else {
 		RunPrompt()
 	}
 }
 
 func RunFile(path string) {
-	b, err := ioutil.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		log.Fatal(err)
 	}
-	sc := scanner.NewScanner(string(b))
-	run(sc)
+	defer f.Close()
+
+	// Stream the file instead of loading it all
+	sc := scanner.NewReaderScanner(f)
+	sc.SlashComments = *slashComments
+	if !run(&sc) {
+		os.Exit(65)
+	}
+}
+
+// CheckFile reports the type mismatches of a script without running it
+func CheckFile(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	sc := scanner.NewReaderScanner(f)
+	sc.SlashComments = *slashComments
+	p := ast.NewSourceParser(&sc)
+	stmts, err := p.Program()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(65)
+	}
 
-	if sc.HadError {
+	if sc.HadError || !check(stmts) {
 		os.Exit(65)
 	}
 }
@@ -46,24 +77,44 @@ func RunPrompt() {
 			return
 		}
 		sc := scanner.NewScanner(input)
-		run(sc)
+		sc.SlashComments = *slashComments
+		run(&sc)
 		// You had an error, fine, carry on
 		sc.HadError = false
 	}
 }
 
-func run(sc scanner.Scanner) {
-	tokens := sc.ScanTokens()
-
-	p := ast.NewParser(tokens)
-	expr, err := p.Parse()
+// run parses, checks and prints a program, it tells whether it went through
+// without errors
+func run(sc *scanner.Scanner) bool {
+	// Tokens are pulled by the parser as it goes
+	p := ast.NewSourceParser(sc)
+	stmts, err := p.Program()
 	if err != nil {
 		fmt.Println(err)
+		return false
 	}
 
-	if sc.HadError {
-		return
+	if sc.HadError || !check(stmts) {
+		return false
 	}
 
-	fmt.Println(ast.NewPrinter().Print(expr))
+	for _, stmt := range stmts {
+		fmt.Println(ast.NewPrinter().PrintStmt(stmt))
+	}
+	return true
+}
+
+// check reports the type mismatches of a program, it tells whether there
+// were none
+func check(stmts []ast.Stmt) bool {
+	c := ast.NewChecker()
+	errs := c.CheckProgram(stmts)
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	for _, warning := range c.Warnings() {
+		fmt.Print(warning)
+	}
+	return len(errs) == 0
 }
@@ -18,13 +18,76 @@ func main() {
 	dir := "./pkg/ast"
 
 	defineAst(dir, "Expr", []string{
-		"Binary   : left Expr, operator tok.Token, right Expr",
-		"Grouping : expression Expr",
-		"Literal  : value interface{}",
-		"Unary    : operator tok.Token, right Expr",
+		"Assign      : name tok.Token, value Expr",
+		"Await       : keyword tok.Token, value Expr",
+		"Binary      : left Expr, operator tok.Token, right Expr",
+		"Call        : callee Expr, paren tok.Token, arguments []Expr",
+		"Compound    : target Expr, operator tok.Token, value Expr",
+		"Destructure : pattern Pattern, equals tok.Token, value Expr",
+		"Get         : object Expr, name tok.Token",
+		"Grouping    : expression Expr",
+		"Index       : object Expr, bracket tok.Token, index Expr",
+		"IndexSet    : object Expr, bracket tok.Token, index Expr, value Expr",
+		"ListLiteral : elements []Expr",
+		"Literal     : value interface{}",
+		"MapLiteral  : brace tok.Token, keys []Expr, values []Expr",
+		"Match       : keyword tok.Token, value Expr, patterns []Pattern, guards []Expr, bodies []Expr",
+		"Named       : name tok.Token, value Expr",
+		"Set         : object Expr, name tok.Token, value Expr",
+		"Slice       : object Expr, bracket tok.Token, start Expr, end Expr",
+		"Spawn       : keyword tok.Token, call Expr",
+		"Spread      : ellipsis tok.Token, expression Expr",
+		"This        : keyword tok.Token",
+		"Unary       : operator tok.Token, right Expr",
+		"Update      : operator tok.Token, target Expr, prefix bool",
+		"Variable    : name tok.Token",
+		"Yield       : keyword tok.Token, value Expr",
+	})
+
+	defineAst(dir, "Pattern", []string{
+		"Alternatives : patterns []Pattern",
+		"Binding      : name tok.Token",
+		"Constant     : value interface{}",
+		"Instance     : class tok.Token, fields []Pattern",
+		"Mapping      : keys []Expr, values []Pattern",
+		"Rest         : name tok.Token",
+		"Sequence     : elements []Pattern",
+		"Wildcard     : underscore tok.Token",
+	})
+
+	defineAst(dir, "Stmt", []string{
+		"Block  : statements []Stmt",
+		"Break  : keyword tok.Token, label tok.Token",
+		"Class  : name tok.Token, superclass tok.Token, traits []tok.Token, methods []Function, fields []Field",
+		"Const  : name tok.Token, initializer Expr",
+		"Continue : keyword tok.Token, label tok.Token",
+		"Enum   : name tok.Token, variants []tok.Token, fields [][]tok.Token",
+		"Export : keyword tok.Token, declaration Stmt",
+		"Expression : expression Expr",
+		"For    : keyword tok.Token, names []tok.Token, iterable Expr, body Stmt",
+		"Function : name tok.Token, params []Parameter, returns *Annotation, body Stmt, async bool, static bool, kind FunctionKind",
+		"Import : keyword tok.Token, path tok.Token, alias tok.Token, names []tok.Token",
+		"Label  : name tok.Token, loop Stmt",
+		"Loop   : keyword tok.Token, initializer Stmt, condition Expr, increment Expr, body Stmt",
+		"Return : keyword tok.Token, value Expr",
+		"Throw  : keyword tok.Token, value Expr",
+		"Trait  : name tok.Token, methods []Function",
+		"Try    : keyword tok.Token, body Stmt, name tok.Token, handler Stmt, finally Stmt",
+		"Var    : name tok.Token, annotation *Annotation, initializer Expr",
+		"VarPattern : keyword tok.Token, pattern Pattern, initializer Expr",
+		"While  : keyword tok.Token, condition Expr, body Stmt",
 	})
 }
 
+// names returns the Accepter and Visitor interfaces names of a base, Expr came
+// first and keeps them unprefixed
+func names(baseName string) (string, string) {
+	if baseName == "Expr" {
+		return "Accepter", "Visitor"
+	}
+	return baseName + "Accepter", baseName + "Visitor"
+}
+
 func defineAst(outputDir, baseName string, types []string) error {
 
 	path := outputDir + "/" + strings.ToLower(baseName) + ".go"
@@ -40,13 +103,15 @@ func defineAst(outputDir, baseName string, types []string) error {
 	buf.WriteString("\n")
 	buf.WriteString("import tok \"github.com/cedricmar/bazic/pkg/token\"\n")
 
+	accepter, visitor := names(baseName)
+
 	buf.WriteString("\n")
 	buf.WriteString("// " + baseName + " is a type for the AST\n")
-	buf.WriteString("type " + baseName + " Accepter\n")
+	buf.WriteString("type " + baseName + " " + accepter + "\n")
 
 	buf.WriteString("\n")
-	buf.WriteString("type Accepter interface {\n")
-	buf.WriteString("	Accept(v Visitor) interface{}\n")
+	buf.WriteString("type " + accepter + " interface {\n")
+	buf.WriteString("	Accept(v " + visitor + ") interface{}\n")
 	buf.WriteString("}\n")
 
 	// Define the Visitor interface
@@ -84,8 +149,9 @@ func defineAst(outputDir, baseName string, types []string) error {
 
 func defineVisitor(buf *bytes.Buffer, baseName string, types []string) {
 	buf.WriteString("\n")
-	buf.WriteString("// Visitor allows to add features to Types\n")
-	buf.WriteString("type Visitor interface {\n")
+	_, visitor := names(baseName)
+	buf.WriteString("// " + visitor + " allows to add features to Types\n")
+	buf.WriteString("type " + visitor + " interface {\n")
 
 	for _, t := range types {
 		typeName := strings.Trim(strings.Split(t, ":")[0], " ")
@@ -130,7 +196,12 @@ func defineType(buf *bytes.Buffer, baseName, class, fields string) {
 	// Visitor pattern
 	buf.WriteString("\n")
 	v := strings.ToLower(string(class[0]))
-	buf.WriteString("func (" + v + " " + class + ") Accept(v Visitor) interface{} {\n")
+	// The visitor is already v
+	if v == "v" {
+		v = strings.ToLower(class[:2])
+	}
+	_, visitor := names(baseName)
+	buf.WriteString("func (" + v + " " + class + ") Accept(v " + visitor + ") interface{} {\n")
 	buf.WriteString("    return v.Visit" + class + baseName + "(" + v + ")\n")
 	buf.WriteString("}\n")
 }
@@ -0,0 +1,28 @@
+package ast
+
+import (
+	"strings"
+
+	tok "github.com/cedricmar/bazic/pkg/token"
+)
+
+// Annotation is a type written in the source, such as List<Number>
+type Annotation struct {
+	name      tok.Token
+	arguments []Annotation
+}
+
+func NewAnnotation(name tok.Token, arguments []Annotation) Annotation {
+	return Annotation{name: name, arguments: arguments}
+}
+
+func (a Annotation) String() string {
+	if a.arguments == nil {
+		return a.name.Lexeme
+	}
+	var arguments []string
+	for _, argument := range a.arguments {
+		arguments = append(arguments, argument.String())
+	}
+	return a.name.Lexeme + "<" + strings.Join(arguments, ", ") + ">"
+}
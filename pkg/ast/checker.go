@@ -0,0 +1,1001 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/cedricmar/bazic/pkg/scanner"
+	tok "github.com/cedricmar/bazic/pkg/token"
+)
+
+// Type is a static type inferred by the Checker
+type Type int
+
+const (
+	// Anything, the checker could not tell
+	DYNAMIC_TYPE Type = iota
+	BOOL_TYPE
+	LIST_TYPE
+	MAP_TYPE
+	NIL_TYPE
+	NUMBER_TYPE
+	STRING_TYPE
+)
+
+func (t Type) String() string {
+	switch t {
+	case BOOL_TYPE:
+		return "Bool"
+	case LIST_TYPE:
+		return "List"
+	case MAP_TYPE:
+		return "Map"
+	case NIL_TYPE:
+		return "Nil"
+	case NUMBER_TYPE:
+		return "Number"
+	case STRING_TYPE:
+		return "String"
+	}
+	return "Dynamic"
+}
+
+// TypeError is an operation the Checker knows will fail at runtime. It only
+// knows the line of its token, not the span of the faulty expression.
+type TypeError struct {
+	token tok.Token
+	msg   string
+}
+
+func (e TypeError) Error() string {
+	scanner.Error(e.token, e.msg)
+	return ""
+}
+
+// Warning is a likely mistake the Checker found, the program may still run
+type Warning struct {
+	token tok.Token
+	msg   string
+}
+
+func (w Warning) String() string {
+	scanner.Warn(w.token, w.msg)
+	return ""
+}
+
+// Checker is a gradual type checker, it infers the types of expressions
+// and reports the mismatches it is sure of. Whatever it cannot tell is
+// dynamic and left for the runtime to check, operators on dynamic operands
+// included since instances overload them with special methods.
+type Checker struct {
+	locals map[string]Type
+	// Types of the annotated variables, they must keep them
+	declared map[string]Type
+	// Constants, true when declared by the function being checked rather
+	// than an enclosing one
+	constants map[string]bool
+	// Set while checking the pattern of a destructuring assignment, or of a
+	// variable declaration
+	destructuring bool
+	declaring     bool
+	// Type the function being checked returns, dynamic when not annotated
+	returns Type
+	// Number of class bodies being checked, their private members are
+	// reachable there only
+	classes int
+	// Loops being checked, innermost last, and the label of the next one
+	loops  []loopScope
+	label  string
+	traits map[string]Trait
+	// Variants of the enums declared, and the enum of each variant
+	enums    map[string][]tok.Token
+	variants map[string]string
+	errors   []TypeError
+	warnings []Warning
+}
+
+// loopScope keeps the locals at each 'continue' of a loop, they reach its
+// increment as much as the end of its body does
+type loopScope struct {
+	label     string
+	continues []map[string]Type
+}
+
+func NewChecker() Checker {
+	return Checker{
+		locals:    map[string]Type{},
+		declared:  map[string]Type{},
+		constants: map[string]bool{},
+		returns:   DYNAMIC_TYPE,
+		traits:    map[string]Trait{},
+		enums:     map[string][]tok.Token{},
+		variants:  map[string]string{},
+	}
+}
+
+// annotations are the types known by name, with the number of type
+// arguments they take
+var annotations = map[string]struct {
+	t     Type
+	arity int
+}{
+	"Bool":   {BOOL_TYPE, 0},
+	"List":   {LIST_TYPE, 1},
+	"Map":    {MAP_TYPE, 2},
+	"Nil":    {NIL_TYPE, 0},
+	"Number": {NUMBER_TYPE, 0},
+	"String": {STRING_TYPE, 0},
+}
+
+// Check returns the type of expr along with the mismatches found in it,
+// the types of the variables it assigns are kept for the next checks
+func (c *Checker) Check(expr Expr) (Type, []TypeError) {
+	c.errors = nil
+	t := c.check(expr)
+	return t, c.errors
+}
+
+// CheckProgram returns the mismatches found in the statements, Warnings
+// returns what else looked wrong
+func (c *Checker) CheckProgram(stmts []Stmt) []TypeError {
+	c.errors, c.warnings = nil, nil
+	// A class may use a trait declared further down the program
+	for _, stmt := range stmts {
+		if export, ok := stmt.(Export); ok {
+			stmt = export.declaration
+		}
+		if trait, ok := stmt.(Trait); ok {
+			c.traits[trait.name.Lexeme] = trait
+		}
+	}
+	for _, stmt := range stmts {
+		stmt.Accept(c)
+	}
+	return c.errors
+}
+
+// Warnings returns the likely mistakes found by the last check
+func (c *Checker) Warnings() []Warning {
+	return c.warnings
+}
+
+func (c *Checker) check(expr Expr) Type {
+	return expr.Accept(c).(Type)
+}
+
+func (c *Checker) VisitAssignExpr(expr Assign) interface{} {
+	t := c.check(expr.value)
+	c.assign(expr.name, t)
+	return t
+}
+
+func (c *Checker) VisitAwaitExpr(expr Await) interface{} {
+	c.check(expr.value)
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitBinaryExpr(expr Binary) interface{} {
+	return c.binary(c.check(expr.left), expr.operator, c.check(expr.right))
+}
+
+func (c *Checker) VisitCallExpr(expr Call) interface{} {
+	if c.check(expr.callee) != DYNAMIC_TYPE {
+		c.error(expr.paren, "Can only call functions and classes.")
+	}
+	for _, argument := range expr.arguments {
+		c.check(argument)
+	}
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitCompoundExpr(expr Compound) interface{} {
+	target := c.check(expr.target)
+	value := c.check(expr.value)
+
+	// "+=" checks as "+" would
+	operator := expr.operator
+	operator.TokenType = map[tok.TokenType]tok.TokenType{
+		tok.PLUS_EQUAL:    tok.PLUS,
+		tok.MINUS_EQUAL:   tok.MINUS,
+		tok.STAR_EQUAL:    tok.STAR,
+		tok.SLASH_EQUAL:   tok.SLASH,
+		tok.PERCENT_EQUAL: tok.PERCENT,
+	}[operator.TokenType]
+	t := c.binary(target, operator, value)
+
+	if v, ok := expr.target.(Variable); ok {
+		c.assign(v.name, t)
+	}
+	return t
+}
+
+func (c *Checker) VisitDestructureExpr(expr Destructure) interface{} {
+	t := c.check(expr.value)
+	c.destructuring = true
+	expr.pattern.Accept(c)
+	c.destructuring = false
+	return t
+}
+
+func (c *Checker) VisitGetExpr(expr Get) interface{} {
+	c.check(expr.object)
+	c.private(expr.name)
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitGroupingExpr(expr Grouping) interface{} {
+	return c.check(expr.expression)
+}
+
+func (c *Checker) VisitIndexExpr(expr Index) interface{} {
+	object := c.subscript(c.check(expr.object), expr.bracket, expr.index)
+	if object == STRING_TYPE {
+		return STRING_TYPE
+	}
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitIndexSetExpr(expr IndexSet) interface{} {
+	c.subscript(c.check(expr.object), expr.bracket, expr.index)
+	return c.check(expr.value)
+}
+
+func (c *Checker) VisitListLiteralExpr(expr ListLiteral) interface{} {
+	for _, element := range expr.elements {
+		c.check(element)
+	}
+	return LIST_TYPE
+}
+
+func (c *Checker) VisitLiteralExpr(expr Literal) interface{} {
+	switch expr.value.(type) {
+	case nil:
+		return NIL_TYPE
+	case bool:
+		return BOOL_TYPE
+	case float64, int:
+		return NUMBER_TYPE
+	case string:
+		return STRING_TYPE
+	}
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitMapLiteralExpr(expr MapLiteral) interface{} {
+	for i := range expr.keys {
+		if !hashable(c.check(expr.keys[i])) {
+			c.error(expr.brace, "Map keys can't be lists or maps.")
+		}
+		c.check(expr.values[i])
+	}
+	return MAP_TYPE
+}
+
+func (c *Checker) VisitMatchExpr(expr Match) interface{} {
+	c.check(expr.value)
+
+	// Any arm may run, a variable keeps its type only when they all agree
+	before := c.locals
+	arms := []map[string]Type{before}
+	var t Type
+	for i, pattern := range expr.patterns {
+		c.locals = copyLocals(before)
+		pattern.Accept(c)
+		if expr.guards[i] != nil {
+			c.check(expr.guards[i])
+		}
+		body := c.check(expr.bodies[i])
+		if i == 0 {
+			t = body
+		} else if body != t {
+			t = DYNAMIC_TYPE
+		}
+		arms = append(arms, c.locals)
+	}
+	c.locals = mergeLocals(arms)
+	c.exhaustive(expr)
+	return t
+}
+
+func (c *Checker) VisitNamedExpr(expr Named) interface{} {
+	return c.check(expr.value)
+}
+
+func (c *Checker) VisitSetExpr(expr Set) interface{} {
+	c.check(expr.object)
+	c.private(expr.name)
+	return c.check(expr.value)
+}
+
+func (c *Checker) VisitSliceExpr(expr Slice) interface{} {
+	object := c.check(expr.object)
+	if object != DYNAMIC_TYPE && object != LIST_TYPE && object != STRING_TYPE {
+		c.error(expr.bracket, "Can only slice lists and strings.")
+		object = DYNAMIC_TYPE
+	}
+	for _, bound := range []Expr{expr.start, expr.end} {
+		if bound != nil && !c.is(c.check(bound), NUMBER_TYPE) {
+			c.error(expr.bracket, "Slice bounds must be numbers.")
+		}
+	}
+	return object
+}
+
+func (c *Checker) VisitSpawnExpr(expr Spawn) interface{} {
+	c.check(expr.call)
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitSpreadExpr(expr Spread) interface{} {
+	if !c.is(c.check(expr.expression), LIST_TYPE) {
+		c.error(expr.ellipsis, "Can only spread lists.")
+	}
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitThisExpr(expr This) interface{} {
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitUnaryExpr(expr Unary) interface{} {
+	right := c.check(expr.right)
+	if expr.operator.TokenType == tok.BANG {
+		return BOOL_TYPE
+	}
+	// Maybe an instance with __neg__ or __invert__
+	if right == DYNAMIC_TYPE {
+		return DYNAMIC_TYPE
+	}
+	if right != NUMBER_TYPE {
+		c.error(expr.operator, "Operand must be a number.")
+		return DYNAMIC_TYPE
+	}
+	return NUMBER_TYPE
+}
+
+func (c *Checker) VisitUpdateExpr(expr Update) interface{} {
+	if v, ok := expr.target.(Variable); ok {
+		c.reassign(v.name)
+	}
+	// "x++" adds 1 as "x += 1" would, through __add__ for an instance
+	target := c.check(expr.target)
+	if target == DYNAMIC_TYPE {
+		return DYNAMIC_TYPE
+	}
+	if target != NUMBER_TYPE {
+		c.error(expr.operator, "Operand must be a number.")
+	} else if v, ok := expr.target.(Variable); ok {
+		c.locals[v.name.Lexeme] = NUMBER_TYPE
+	}
+	return NUMBER_TYPE
+}
+
+func (c *Checker) VisitVariableExpr(expr Variable) interface{} {
+	// Unknown variables are dynamic, they may come from anywhere
+	return c.locals[expr.name.Lexeme]
+}
+
+func (c *Checker) VisitYieldExpr(expr Yield) interface{} {
+	if expr.value != nil {
+		c.check(expr.value)
+	}
+	// Whatever send() passes in
+	return DYNAMIC_TYPE
+}
+
+func (c *Checker) VisitBlockStmt(stmt Block) interface{} {
+	// Constants and annotations end with their block, those around may be
+	// shadowed inside it
+	declared, constants := c.declared, c.constants
+	c.declared, c.constants = copyLocals(declared), map[string]bool{}
+	for name := range constants {
+		c.constants[name] = false
+	}
+	for _, s := range stmt.statements {
+		s.Accept(c)
+	}
+	c.declared, c.constants = declared, constants
+	return nil
+}
+
+func (c *Checker) VisitBreakStmt(stmt Break) interface{} {
+	return nil
+}
+
+func (c *Checker) VisitClassStmt(stmt Class) interface{} {
+	c.locals[stmt.name.Lexeme] = DYNAMIC_TYPE
+	c.classes++
+	for _, field := range stmt.fields {
+		c.field(field)
+	}
+	// Static methods belong to the class object, not to its instances
+	defined := map[string]bool{}
+	for _, method := range stmt.methods {
+		c.function(method)
+		if !method.static {
+			defined[method.name.Lexeme] = true
+		}
+	}
+	c.classes--
+
+	// Methods of the class win, the traits must not disagree on the others
+	provided := map[string]string{}
+	var required []string
+	requiredBy := map[string]string{}
+	for _, name := range stmt.traits {
+		trait, ok := c.traits[name.Lexeme]
+		if !ok {
+			continue
+		}
+		for _, method := range trait.methods {
+			m := method.name.Lexeme
+			if defined[m] {
+				continue
+			}
+			if method.body == nil {
+				if _, ok := requiredBy[m]; !ok {
+					required = append(required, m)
+					requiredBy[m] = name.Lexeme
+				}
+				continue
+			}
+			if other, ok := provided[m]; ok {
+				c.error(stmt.name, "Method '"+m+"' is provided by both '"+other+"' and '"+name.Lexeme+"'.")
+				continue
+			}
+			provided[m] = name.Lexeme
+		}
+	}
+	for _, m := range required {
+		if _, ok := provided[m]; !ok {
+			c.error(stmt.name, "Missing method '"+m+"' required by '"+requiredBy[m]+"'.")
+		}
+	}
+	return nil
+}
+
+func (c *Checker) VisitConstStmt(stmt Const) interface{} {
+	t := c.check(stmt.initializer)
+	c.declare(stmt.name)
+	delete(c.declared, stmt.name.Lexeme)
+	c.locals[stmt.name.Lexeme] = t
+	c.constants[stmt.name.Lexeme] = true
+	return nil
+}
+
+func (c *Checker) VisitContinueStmt(stmt Continue) interface{} {
+	for i := len(c.loops) - 1; i >= 0; i-- {
+		if stmt.label.Lexeme == "" || c.loops[i].label == stmt.label.Lexeme {
+			c.loops[i].continues = append(c.loops[i].continues, copyLocals(c.locals))
+			break
+		}
+	}
+	return nil
+}
+
+func (c *Checker) VisitEnumStmt(stmt Enum) interface{} {
+	c.enums[stmt.name.Lexeme] = stmt.variants
+	for _, variant := range stmt.variants {
+		c.variants[variant.Lexeme] = stmt.name.Lexeme
+	}
+	return nil
+}
+
+func (c *Checker) VisitExportStmt(stmt Export) interface{} {
+	return stmt.declaration.Accept(c)
+}
+
+func (c *Checker) VisitExpressionStmt(stmt Expression) interface{} {
+	c.check(stmt.expression)
+	return nil
+}
+
+func (c *Checker) VisitForStmt(stmt For) interface{} {
+	iterable := c.check(stmt.iterable)
+	switch iterable {
+	case DYNAMIC_TYPE, LIST_TYPE, MAP_TYPE, STRING_TYPE:
+	default:
+		c.error(stmt.keyword, "Can only iterate over lists, maps and strings.")
+	}
+
+	variables := map[string]Type{}
+	for _, name := range stmt.names {
+		variables[name.Lexeme] = DYNAMIC_TYPE
+	}
+	if iterable == STRING_TYPE && len(stmt.names) == 1 {
+		variables[stmt.names[0].Lexeme] = STRING_TYPE
+	}
+	c.loop(variables, stmt.body)
+	return nil
+}
+
+func (c *Checker) VisitFunctionStmt(stmt Function) interface{} {
+	c.declare(stmt.name)
+	delete(c.declared, stmt.name.Lexeme)
+	c.locals[stmt.name.Lexeme] = DYNAMIC_TYPE
+	c.function(stmt)
+	return nil
+}
+
+func (c *Checker) VisitImportStmt(stmt Import) interface{} {
+	return nil
+}
+
+func (c *Checker) VisitLabelStmt(stmt Label) interface{} {
+	c.label = stmt.name.Lexeme
+	return stmt.loop.Accept(c)
+}
+
+func (c *Checker) VisitLoopStmt(stmt Loop) interface{} {
+	if stmt.initializer != nil {
+		stmt.initializer.Accept(c)
+	}
+	if stmt.condition != nil {
+		c.check(stmt.condition)
+	}
+
+	// The increment runs after the body, on every pass, and after every
+	// 'continue' too
+	before := c.locals
+	c.locals = copyLocals(before)
+	c.enterLoop()
+	stmt.body.Accept(c)
+	continues := c.leaveLoop()
+	c.locals = mergeLocals(append([]map[string]Type{c.locals}, continues...))
+	if stmt.increment != nil {
+		c.check(stmt.increment)
+	}
+	c.locals = mergeLocals([]map[string]Type{before, c.locals})
+	return nil
+}
+
+func (c *Checker) VisitReturnStmt(stmt Return) interface{} {
+	t := NIL_TYPE
+	if stmt.value != nil {
+		t = c.check(stmt.value)
+	}
+	if c.returns != DYNAMIC_TYPE && !c.is(t, c.returns) {
+		c.error(stmt.keyword, "Can't return "+t.String()+" from a function returning "+c.returns.String()+".")
+	}
+	return nil
+}
+
+func (c *Checker) VisitThrowStmt(stmt Throw) interface{} {
+	c.check(stmt.value)
+	return nil
+}
+
+func (c *Checker) VisitTraitStmt(stmt Trait) interface{} {
+	c.locals[stmt.name.Lexeme] = DYNAMIC_TYPE
+	c.traits[stmt.name.Lexeme] = stmt
+	for _, method := range stmt.methods {
+		c.function(method)
+	}
+	return nil
+}
+
+func (c *Checker) VisitTryStmt(stmt Try) interface{} {
+	// The body may stop anywhere, the handler may run or not
+	before := c.locals
+	c.locals = copyLocals(before)
+	stmt.body.Accept(c)
+	branches := []map[string]Type{before, c.locals}
+	if stmt.handler != nil {
+		c.locals = copyLocals(before)
+		c.locals[stmt.name.Lexeme] = DYNAMIC_TYPE
+		stmt.handler.Accept(c)
+		branches = append(branches, c.locals)
+	}
+	c.locals = mergeLocals(branches)
+
+	if stmt.finally != nil {
+		stmt.finally.Accept(c)
+	}
+	return nil
+}
+
+func (c *Checker) VisitVarStmt(stmt Var) interface{} {
+	t := c.check(stmt.initializer)
+	c.declare(stmt.name)
+	delete(c.declared, stmt.name.Lexeme)
+	if stmt.annotation != nil {
+		c.declared[stmt.name.Lexeme] = c.annotated(*stmt.annotation)
+	}
+	c.assign(stmt.name, t)
+	return nil
+}
+
+func (c *Checker) VisitVarPatternStmt(stmt VarPattern) interface{} {
+	c.check(stmt.initializer)
+	c.declaring = true
+	stmt.pattern.Accept(c)
+	c.declaring = false
+	return nil
+}
+
+func (c *Checker) VisitWhileStmt(stmt While) interface{} {
+	c.check(stmt.condition)
+	c.loop(nil, stmt.body)
+	return nil
+}
+
+// Patterns bind dynamic values
+func (c *Checker) VisitAlternativesPattern(pattern Alternatives) interface{} {
+	for _, p := range pattern.patterns {
+		p.Accept(c)
+	}
+	return nil
+}
+
+func (c *Checker) VisitBindingPattern(pattern Binding) interface{} {
+	c.bind(pattern.name)
+	c.locals[pattern.name.Lexeme] = DYNAMIC_TYPE
+	return nil
+}
+
+func (c *Checker) VisitConstantPattern(pattern Constant) interface{} {
+	return nil
+}
+
+func (c *Checker) VisitInstancePattern(pattern Instance) interface{} {
+	for _, p := range pattern.fields {
+		p.Accept(c)
+	}
+	return nil
+}
+
+func (c *Checker) VisitMappingPattern(pattern Mapping) interface{} {
+	for _, p := range pattern.values {
+		p.Accept(c)
+	}
+	return nil
+}
+
+func (c *Checker) VisitRestPattern(pattern Rest) interface{} {
+	c.bind(pattern.name)
+	c.locals[pattern.name.Lexeme] = LIST_TYPE
+	return nil
+}
+
+func (c *Checker) VisitSequencePattern(pattern Sequence) interface{} {
+	for _, p := range pattern.elements {
+		p.Accept(c)
+	}
+	return nil
+}
+
+func (c *Checker) VisitWildcardPattern(pattern Wildcard) interface{} {
+	return nil
+}
+
+// loop checks a loop body with its variables, the body runs any number of
+// times, maybe none
+func (c *Checker) loop(variables map[string]Type, body Stmt) {
+	before := c.locals
+	c.locals = copyLocals(before)
+	for name, t := range variables {
+		c.locals[name] = t
+	}
+	c.enterLoop()
+	body.Accept(c)
+	c.leaveLoop()
+	c.locals = mergeLocals([]map[string]Type{before, c.locals})
+}
+
+// assign records the type of a variable, an annotated one must keep its
+// declared type
+func (c *Checker) assign(name tok.Token, t Type) {
+	c.reassign(name)
+	if declared := c.declared[name.Lexeme]; declared != DYNAMIC_TYPE {
+		if !c.is(t, declared) {
+			c.error(name, "Can't assign "+t.String()+" to a variable of type "+declared.String()+".")
+		}
+		t = declared
+	}
+	c.locals[name.Lexeme] = t
+}
+
+// function checks the body of a function or method
+func (c *Checker) function(stmt Function) {
+	// Defaults are evaluated where the function is declared
+	types := map[string]Type{}
+	for _, param := range stmt.params {
+		types[param.name.Lexeme] = c.parameter(param)
+	}
+
+	// A required method only has its annotations checked
+	if stmt.body == nil {
+		if stmt.returns != nil {
+			c.annotated(*stmt.returns)
+		}
+		return
+	}
+
+	// The body runs later, with arguments of their annotated types or
+	// dynamic ones
+	before, declared, constants, loops, returns := c.locals, c.declared, c.constants, c.loops, c.returns
+	c.locals, c.declared, c.loops = copyLocals(before), copyLocals(declared), nil
+	c.constants = map[string]bool{}
+	for name := range constants {
+		c.constants[name] = false
+	}
+	for _, param := range stmt.params {
+		name := param.name.Lexeme
+		c.locals[name] = types[name]
+		delete(c.declared, name)
+		delete(c.constants, name)
+		if param.annotation != nil {
+			c.declared[name] = types[name]
+		}
+	}
+	c.returns = DYNAMIC_TYPE
+	if stmt.returns != nil {
+		c.returns = c.annotated(*stmt.returns)
+	}
+	stmt.body.Accept(c)
+	c.locals, c.declared, c.constants, c.loops, c.returns = before, declared, constants, loops, returns
+}
+
+// parameter returns the type of a parameter in the function body, a rest
+// parameter always holds a list
+func (c *Checker) parameter(param Parameter) Type {
+	t := DYNAMIC_TYPE
+	if param.annotation != nil {
+		t = c.annotated(*param.annotation)
+	}
+	if param.rest {
+		if !c.is(t, LIST_TYPE) {
+			c.error(param.name, "Rest parameter must be a List.")
+		}
+		return LIST_TYPE
+	}
+	if param.value != nil {
+		value := c.check(param.value)
+		if t != DYNAMIC_TYPE && !c.is(value, t) {
+			c.error(param.name, "Can't default a parameter of type "+t.String()+" to "+value.String()+".")
+		}
+	}
+	return t
+}
+
+// field checks the initial value of a field against its annotation
+func (c *Checker) field(field Field) {
+	t := DYNAMIC_TYPE
+	if field.value != nil {
+		t = c.check(field.value)
+	}
+	if field.annotation != nil {
+		declared := c.annotated(*field.annotation)
+		if declared != DYNAMIC_TYPE && !c.is(t, declared) {
+			c.error(field.name, "Can't assign "+t.String()+" to a field of type "+declared.String()+".")
+		}
+	}
+}
+
+// private reports a private member, named with a leading '_', reached from
+// outside a class body
+func (c *Checker) private(name tok.Token) {
+	if c.classes == 0 && len(name.Lexeme) > 1 && strings.HasPrefix(name.Lexeme, "_") {
+		c.error(name, "Can't access private member '"+name.Lexeme+"' outside a class.")
+	}
+}
+
+// exhaustive warns about a match over the variants of an enum that misses
+// some of them without an arm catching anything else
+func (c *Checker) exhaustive(expr Match) {
+	enum := ""
+	covered := map[string]bool{}
+	for i, pattern := range expr.patterns {
+		alternatives := []Pattern{pattern}
+		if a, ok := pattern.(Alternatives); ok {
+			alternatives = a.patterns
+		}
+		for _, alternative := range alternatives {
+			variant := ""
+			switch pt := alternative.(type) {
+			case Binding:
+				variant = pt.name.Lexeme
+				if _, ok := c.variants[variant]; !ok && expr.guards[i] == nil {
+					return
+				}
+			case Wildcard:
+				if expr.guards[i] == nil {
+					return
+				}
+			case Instance:
+				// Only a variant whose fields match anything is covered
+				variant = pt.class.Lexeme
+				for _, field := range pt.fields {
+					if !c.irrefutable(field) {
+						variant = ""
+					}
+				}
+			}
+			if e, ok := c.variants[variant]; ok && enum == "" {
+				enum = e
+			}
+			if c.variants[variant] == enum && expr.guards[i] == nil {
+				covered[variant] = true
+			}
+		}
+	}
+	if enum == "" {
+		return
+	}
+
+	var missing []string
+	for _, variant := range c.enums[enum] {
+		if !covered[variant.Lexeme] {
+			missing = append(missing, "'"+variant.Lexeme+"'")
+		}
+	}
+	if missing != nil {
+		c.warnings = append(c.warnings, Warning{expr.keyword, "Match on enum '" + enum + "' misses " + strings.Join(missing, ", ") + "."})
+	}
+}
+
+// irrefutable tells whether a pattern matches any value
+func (c *Checker) irrefutable(pattern Pattern) bool {
+	switch pt := pattern.(type) {
+	case Binding:
+		_, variant := c.variants[pt.name.Lexeme]
+		return !variant
+	case Rest, Wildcard:
+		return true
+	case Alternatives:
+		for _, alternative := range pt.patterns {
+			if c.irrefutable(alternative) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enterLoop opens the scope of a loop, taking the pending label
+func (c *Checker) enterLoop() {
+	c.loops = append(c.loops, loopScope{label: c.label})
+	c.label = ""
+}
+
+// leaveLoop closes the innermost loop and returns the locals at its
+// 'continue' statements
+func (c *Checker) leaveLoop() []map[string]Type {
+	scope := c.loops[len(c.loops)-1]
+	c.loops = c.loops[:len(c.loops)-1]
+	return scope.continues
+}
+
+// bind checks a name bound by a pattern, which may assign or declare it
+func (c *Checker) bind(name tok.Token) {
+	switch {
+	case c.destructuring:
+		c.reassign(name)
+	case c.declaring:
+		c.declare(name)
+		delete(c.declared, name.Lexeme)
+	}
+}
+
+// reassign reports an assignment to a constant
+func (c *Checker) reassign(name tok.Token) {
+	if _, ok := c.constants[name.Lexeme]; ok {
+		c.error(name, "Can't assign to constant '"+name.Lexeme+"'.")
+	}
+}
+
+// declare reports a declaration reusing the name of a constant of the same
+// function, an enclosing function's constant is only shadowed
+func (c *Checker) declare(name tok.Token) {
+	if c.constants[name.Lexeme] {
+		c.error(name, "Already a constant with this name.")
+	}
+	delete(c.constants, name.Lexeme)
+}
+
+// annotated returns the type of an annotation, names the checker does not
+// know such as classes are dynamic
+func (c *Checker) annotated(a Annotation) Type {
+	for _, argument := range a.arguments {
+		c.annotated(argument)
+	}
+	known, ok := annotations[a.name.Lexeme]
+	if !ok {
+		return DYNAMIC_TYPE
+	}
+	if a.arguments != nil && len(a.arguments) != known.arity {
+		c.error(a.name, "Wrong number of type arguments for '"+a.name.Lexeme+"'.")
+	}
+	return known.t
+}
+
+// binary checks the operands of a binary operator and returns its type
+func (c *Checker) binary(left Type, operator tok.Token, right Type) Type {
+	// A dynamic operand may be an instance with special methods such as
+	// __add__, or their reflected __radd__, returning anything. The README
+	// lists the methods and the order they are tried in.
+	if left == DYNAMIC_TYPE || right == DYNAMIC_TYPE {
+		return DYNAMIC_TYPE
+	}
+
+	switch operator.TokenType {
+	case tok.EQUAL_EQUAL, tok.BANG_EQUAL:
+		return BOOL_TYPE
+	case tok.GREATER, tok.GREATER_EQUAL, tok.LESS, tok.LESS_EQUAL:
+		if left != NUMBER_TYPE || right != NUMBER_TYPE {
+			c.error(operator, "Operands must be numbers.")
+		}
+		return BOOL_TYPE
+	case tok.PLUS:
+		if left != right || (left != NUMBER_TYPE && left != STRING_TYPE) {
+			c.error(operator, "Operands must be two numbers or two strings.")
+			return DYNAMIC_TYPE
+		}
+		return left
+	}
+
+	if left != NUMBER_TYPE || right != NUMBER_TYPE {
+		c.error(operator, "Operands must be numbers.")
+		return DYNAMIC_TYPE
+	}
+	return NUMBER_TYPE
+}
+
+// subscript checks that object can be indexed by index and returns the
+// type of the object
+func (c *Checker) subscript(object Type, bracket tok.Token, index Expr) Type {
+	i := c.check(index)
+	switch object {
+	case LIST_TYPE, STRING_TYPE:
+		if !c.is(i, NUMBER_TYPE) {
+			c.error(bracket, "Index must be a number.")
+		}
+	case MAP_TYPE:
+		if !hashable(i) {
+			c.error(bracket, "Map keys can't be lists or maps.")
+		}
+	case DYNAMIC_TYPE:
+	default:
+		c.error(bracket, "Can only index lists, maps and strings.")
+		return DYNAMIC_TYPE
+	}
+	return object
+}
+
+// hashable tells whether a value of type t may be a map key, lists and maps
+// change under the key
+func hashable(t Type) bool {
+	return t != LIST_TYPE && t != MAP_TYPE
+}
+
+// is tells whether a value of type t may be of type want
+func (c *Checker) is(t, want Type) bool {
+	return t == DYNAMIC_TYPE || t == want
+}
+
+func (c *Checker) error(token tok.Token, msg string) {
+	c.errors = append(c.errors, TypeError{token, msg})
+}
+
+// mergeLocals keeps the type of a variable when every branch agrees on it
+func mergeLocals(branches []map[string]Type) map[string]Type {
+	merged := map[string]Type{}
+	for _, branch := range branches {
+		for name, local := range branch {
+			for _, other := range branches {
+				if other[name] != local {
+					local = DYNAMIC_TYPE
+				}
+			}
+			merged[name] = local
+		}
+	}
+	return merged
+}
+
+func copyLocals(locals map[string]Type) map[string]Type {
+	copied := map[string]Type{}
+	for name, t := range locals {
+		copied[name] = t
+	}
+	return copied
+}
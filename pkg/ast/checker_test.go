@@ -0,0 +1,277 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckerTypes(t *testing.T) {
+	tests := map[string]Type{
+		"1 + 2 * 3":                          NUMBER_TYPE,
+		"\"a\" + \"b\"":                      STRING_TYPE,
+		"1 < 2 == !nil":                      BOOL_TYPE,
+		"[1, \"a\"]":                         LIST_TYPE,
+		"{\"a\": 1}":                         MAP_TYPE,
+		"nil":                                NIL_TYPE,
+		"x":                                  DYNAMIC_TYPE,
+		"x + 1":                              DYNAMIC_TYPE,
+		"\"abc\"[0]":                         STRING_TYPE,
+		"[1][0]":                             DYNAMIC_TYPE,
+		"[1, 2][1:]":                         LIST_TYPE,
+		"f(1)":                               DYNAMIC_TYPE,
+		"x = \"a\"":                          STRING_TYPE,
+		"x++":                                DYNAMIC_TYPE,
+		"1 + x":                              DYNAMIC_TYPE,
+		"x + \"a\"":                          DYNAMIC_TYPE,
+		"x < 1":                              DYNAMIC_TYPE,
+		"x == nil":                           DYNAMIC_TYPE,
+		"-x":                                 DYNAMIC_TYPE,
+		"~x":                                 DYNAMIC_TYPE,
+		"!x":                                 BOOL_TYPE,
+		"x[\"a\"]":                           DYNAMIC_TYPE,
+		"[1][x]":                             DYNAMIC_TYPE,
+		"x += \"a\"":                         DYNAMIC_TYPE,
+		"match x { 1 => \"a\", _ => \"b\" }": STRING_TYPE,
+		"match x { 1 => \"a\", _ => 2 }":     DYNAMIC_TYPE,
+		"await 1":                            DYNAMIC_TYPE,
+	}
+
+	for src, want := range tests {
+		expr, err := parse(src)
+		if assert.Nil(t, err, src) {
+			c := NewChecker()
+			got, errs := c.Check(expr)
+			assert.Empty(t, errs, src)
+			assert.Equal(t, want, got, src)
+		}
+	}
+}
+
+func TestCheckerErrors(t *testing.T) {
+	tests := map[string]string{
+		"\"a\" - 1":                              "-",
+		"1 + \"a\"":                              "+",
+		"true + true":                            "+",
+		"x + 1 + (1 + \"a\")":                    "+",
+		"-\"a\"":                                 "-",
+		"~[1]":                                   "~",
+		"nil < 1":                                "<",
+		"1 // {}":                                "//",
+		"1(2)":                                   ")",
+		"1[0]":                                   "[",
+		"[1][\"a\"]":                             "[",
+		"{[1]: 2}":                               "{",
+		"{1: 2, {}: 3}":                          "{",
+		"{}[[1]]":                                "[",
+		"{}[{}] = 1":                             "[",
+		"{\"a\": 1}[[]] += 1":                    "[",
+		"nil[1:2]":                               "[",
+		"[1][\"a\":]":                            "[",
+		"[...1]":                                 "...",
+		"\"a\"[0]++":                             "++",
+		"[x = \"a\", x - 1]":                     "-",
+		"[x = 1, x += \"a\"]":                    "+=",
+		"[x = 1, match y { _ => 2 }, x - \"a\"]": "-",
+	}
+
+	for src, want := range tests {
+		expr, err := parse(src)
+		if assert.Nil(t, err, src) {
+			c := NewChecker()
+			_, errs := c.Check(expr)
+			if assert.Len(t, errs, 1, src) {
+				assert.Equal(t, want, errs[0].token.Lexeme, src)
+			}
+		}
+	}
+}
+
+func TestCheckerProgram(t *testing.T) {
+	tests := map[string]int{
+		"for (x in [1]) x + 1;":                                                              0,
+		"for (c in \"ab\") c + 1;":                                                           1,
+		"for (k, v in {}) k - v;":                                                            0,
+		"for (x in 1) {}":                                                                    1,
+		"y = 1; for (x in xs) { y = \"a\"; } y - 1":                                          0,
+		"y = 1; for (x in xs) { y = 2; } y - \"a\"":                                          1,
+		"y = 1; while (z) { y = \"a\"; break; } y - 1":                                       0,
+		"a: while (\"a\" - 1) { continue a; }":                                               1,
+		"for (var i = 0; i < 3; i++) i + 1;":                                                 0,
+		"for (var i = 0; i < \"a\" - 1; i++) {}":                                             1,
+		"for (var i = 0; i < 3; i = i - \"a\") {}":                                           1,
+		"y = 1; for (;;) { y = \"a\"; } y - 1":                                               0,
+		"for (var i = 0; i < 3; i = i - 1) { i = \"a\"; }":                                   1,
+		"for (var i = 0; i < 3; i = i - 1) { i = 1; continue; i = \"a\"; }":                  0,
+		"a: for (var i = 0;; i = i - 1) { for (x in xs) { i = 1; continue a; } i = \"a\"; }": 0,
+		"for (var i = 0;; i = i - 1) { while (x) { i = 1; continue; } i = \"a\"; }":          1,
+		"for (var i = 0;; i = i - 1) { fun f() { for (;;) continue; } i = \"a\"; }":          1,
+		"throw -\"a\";":                                                      1,
+		"class C { m(x) { return x - 1; } }":                                 0,
+		"class C { m() { return \"a\" - 1; } }":                              1,
+		"x = 1; class C { m(x) { x = \"a\"; } } x - 1":                       0,
+		"trait T { a(); b() {} } class C with T { a() {} }":                  0,
+		"trait T { a(); b() {} } class C with T {}":                          1,
+		"trait T { a(); } trait U { a() {} } class C with T, U {}":           0,
+		"trait T { a() {} } trait U { a() {} } class C with T, U {}":         1,
+		"trait T { a() {} } trait U { a() {} } class C with T, U { a() {} }": 0,
+		"class C with Unknown {}":                                            0,
+		"var n: Number = 1; n + 1":                                           0,
+		"var n: Number = \"a\";":                                             1,
+		"var n: Number = f(); n - \"a\"":                                     1,
+		"var n: Number = 1; n = \"a\";":                                      1,
+		"var n: Number = 1; n = f(); n += 1; n++;":                           0,
+		"var s: String = \"a\"; s += 1;":                                     1,
+		"var xs: List<Number> = [1]; xs = {};":                               1,
+		"var m: Map<String, Money> = {};":                                    0,
+		"var m: Map<String> = {};":                                           1,
+		"var n: Number<String> = 1;":                                         1,
+		"var p: Point = 1; p = \"a\";":                                       0,
+		"var n: Number = 1; var n = \"a\"; n + \"b\"":                        0,
+		"var n: Number = 1; class C { m(n) { n = \"a\"; } }":                 0,
+		"var n: Number = 1; class C { m() { n = \"a\"; } }":                  1,
+		"const c = 1; c + 1":                                                 0,
+		"const c = \"a\"; c - 1":                                             1,
+		"const c = 1; c = 2;":                                                1,
+		"const c = 1; c += 2;":                                               1,
+		"const c = 1; c++;":                                                  1,
+		"const c = 1; [a, c] = [1, 2];":                                      1,
+		"const c = 1; {\"k\": [...c]} = m;":                                  1,
+		"const c = 1; match x { c => c }":                                    0,
+		"const c = [1]; c.x = 2; c[0] = 3;":                                  0,
+		"const c = 1; const c = 2;":                                          1,
+		"const c = 1; var c = 2;":                                            1,
+		"const c = 1; class C { m() { c = 2; } }":                            1,
+		"const c = 1; class C { m(c) { c = 2; } }":                           0,
+		"const c = 1; class C { m() { var c = 2; c = 3; } }":                 0,
+		"const c = 1; class C { m() { const c = 2; c = 3; } }":               1,
+		"var v = 1; const v = 2; v = 3;":                                     1,
+		"fun f(a) { return a - 1; } f(1)":                                    0,
+		"fun f() { return \"a\" - 1; }":                                      1,
+		"const f = 1; fun f() {}":                                            1,
+		"export const c = 1; c = 2;":                                         1,
+		"while (a) { const c = 1; } while (b) { const c = 2; }":              0,
+		"for (x in xs) { const y = x; } for (x in xs) { const y = x; }":      0,
+		"try { const c = 1; } catch (e) { const c = 2; }":                    0,
+		"{ const c = 1; } c = 2;":                                            0,
+		"{ const c = 1; const c = 2; }":                                      1,
+		"{ const c = 1; { c = 2; } }":                                        1,
+		"const c = 1; { c = 2; }":                                            1,
+		"const c = 1; { var c = 2; c = 3; }":                                 0,
+		"const c = 1; { const c = 2; } c = 3;":                               1,
+		"fun f(a: String, b: List<Number>): Bool { return true; }":           0,
+		"fun f(a: String) { return a - 1; }":                                 1,
+		"fun f(a: Number) { return a - 1; }":                                 0,
+		"fun f(a: Number) { a = \"x\"; }":                                    1,
+		"fun f(a) { a = \"x\"; a = 1; }":                                     0,
+		"var a: String = \"\"; fun f(a: Number) { a = 1; } a = \"b\";":       0,
+		"fun f(): Bool { return 1; }":                                        1,
+		"fun f(): Bool { return; }":                                          1,
+		"fun f(): Nil { return; }":                                           0,
+		"fun f(): Number { return x; }":                                      0,
+		"fun f(): Number { fun g() { return \"a\"; } return 1; }":            0,
+		"class C { m(): String { return 1; } }":                              1,
+		"fun f(a: List<Number, String>) {}":                                  1,
+		"trait T { m(a: Map<String>): Number; }":                             1,
+		"fun greet(name, greeting = \"hi\") { return greeting + name; }":     0,
+		"fun f(a: Number = \"a\") {}":                                        1,
+		"fun f(a: Number = 1) { return a - 1; }":                             0,
+		"fun f(a = \"a\") { return a - 1; }":                                 0,
+		"fun f(a = -\"a\") {}":                                               1,
+		"fun sum(...nums) { return nums[0:1]; }":                             0,
+		"fun sum(...nums) { return nums - 1; }":                              1,
+		"fun sum(...nums: List<Number>) {}":                                  0,
+		"fun sum(...nums: Number) {}":                                        1,
+		"trait T { m(a: Number = nil); }":                                    1,
+		"class C { static m() {} w = 0; area { return this.w - 1; } set area(v) { this.w = v; } }": 0,
+		"class C { w: Number = \"a\"; }":                              1,
+		"class C { static n: String = 1; }":                           1,
+		"class C { w = -\"a\"; }":                                     1,
+		"class C { area { return \"a\" - 1; } }":                      1,
+		"class C { _w = 1; m(other) { return this._w + other._w; } }": 0,
+		"class C { _w = 1; m() { fun g() { return this._w; } } }":     0,
+		"c._w;":                              1,
+		"c._w = 1;":                          1,
+		"c._w += 1;":                         1,
+		"c._w++;":                            1,
+		"c.w + c._;":                         0,
+		"class C with T {} trait T { m(); }": 1,
+		"class C with T { m() {} } trait T { m(); }":                      0,
+		"class C with T {} export trait T { m(); }":                       1,
+		"class C with T, U {} trait T { m() {} } trait U { m() {} }":      1,
+		"class C with T {} fun f() { trait T { m(); } }":                  0,
+		"fun g() { (yield 1) - 1; }":                                      0,
+		"var [a, b] = [1, 2]; a - \"x\";":                                 0,
+		"var [a, ...b] = xs; b - 1;":                                      1,
+		"const a = 1; var [a, b] = xs;":                                   1,
+		"const a = 1; { var {a} = m; a = 2; }":                            0,
+		"var n: Number = 1; var [n] = [\"a\"]; n = \"b\";":                0,
+		"var {name, age} = p; name - age;":                                0,
+		"class C { _m() {} } C()._m();":                                   1,
+		"trait T { m(); } class C with T { static m() {} }":               1,
+		"var n: Number = 1; { var n = \"a\"; } n = \"b\";":                1,
+		"{ var n: Number = 1; } n = \"a\";":                               0,
+		"export var n: Number = \"a\";":                                   1,
+		"y = 1; try { y = f(); } catch (e) { e - 1; } y - 1":              0,
+		"y = 1; try { f(); } catch (e) { y = 2; } finally { y - \"a\"; }": 1,
+	}
+
+	for src, n := range tests {
+		stmts, err := program(src)
+		if assert.Nil(t, err, src) {
+			c := NewChecker()
+			assert.Len(t, c.CheckProgram(stmts), n, src)
+		}
+	}
+}
+
+func TestCheckerWarnings(t *testing.T) {
+	tests := map[string]int{
+		"enum C { R, G } match c { R => 1 }":                                     1,
+		"enum C { R, G } match c { R => 1, G => 2 }":                             0,
+		"enum C { R, G } match c { R | G => 1 }":                                 0,
+		"enum C { R, G } match c { R => 1, _ => 2 }":                             0,
+		"enum C { R, G } match c { R => 1, other => 2 }":                         0,
+		"enum C { R, G } match c { R => 1, G if x => 2 }":                        1,
+		"enum C { R, G } match c { R => 1, _ if x => 2 }":                        1,
+		"enum O { None, Some(v) } match o { Some(v) => v }":                      1,
+		"enum O { None, Some(v) } match o { Some(v) => v, None => nil }":         0,
+		"enum O { None, Some(v) } match o { Some(1) => 1, None => nil }":         1,
+		"enum O { None, Some(v) } match o { Some(_) | Some(1) => 1, None => 0 }": 0,
+		"enum S { A(x, y), B } match s { A(x, ...r) => x, B => 0 }":              0,
+		"match x { R => 1 }":                                     0,
+		"match x { 1 => 1, [a] => a }":                           0,
+		"enum C { R, G, B } match c { R => match c { G => 1 } }": 2,
+	}
+
+	for src, n := range tests {
+		stmts, err := program(src)
+		if assert.Nil(t, err, src) {
+			c := NewChecker()
+			assert.Len(t, c.CheckProgram(stmts), 0, src)
+			assert.Len(t, c.Warnings(), n, src)
+		}
+	}
+}
+
+func TestCheckerMatchMergesLocals(t *testing.T) {
+	c := NewChecker()
+	for _, src := range []string{
+		"x = 1",
+		"y = 1",
+		"match z { 1 => x = \"a\", _ => y = 2 }",
+	} {
+		expr, err := parse(src)
+		assert.Nil(t, err, src)
+		_, errs := c.Check(expr)
+		assert.Empty(t, errs, src)
+	}
+
+	// x may now be a string, y is still a number
+	for src, n := range map[string]int{"x + \"a\"": 0, "y + \"a\"": 1} {
+		expr, err := parse(src)
+		assert.Nil(t, err, src)
+		_, errs := c.Check(expr)
+		assert.Len(t, errs, n, src)
+	}
+}
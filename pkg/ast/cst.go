@@ -0,0 +1,924 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+
+	tok "github.com/cedricmar/bazic/pkg/token"
+)
+
+type SyntaxKind int
+
+const (
+	// Leaf holding a single token
+	TOKEN SyntaxKind = iota
+	ROOT
+	ASSIGN
+	AWAIT
+	BINARY
+	CALL
+	COMPOUND
+	DESTRUCTURE
+	GET
+	GROUPING
+	INDEX
+	INDEX_SET
+	LIST
+	LITERAL
+	MAP
+	MATCH
+	NAMED
+	SET
+	SLICE
+	SPAWN
+	SPREAD
+	THIS
+	UNARY
+	UPDATE
+	VARIABLE
+	YIELD
+	// Patterns
+	ALTERNATIVES
+	BINDING
+	CONSTANT
+	INSTANCE
+	MAPPING
+	REST
+	SEQUENCE
+	WILDCARD
+	// Declarations
+	ANNOTATION
+	BLOCK
+	BREAK
+	CLASS
+	CONST
+	CONTINUE
+	ENUM
+	EXPORT
+	EXPRESSION
+	FIELD
+	FOR
+	FUNCTION
+	IMPORT
+	LABEL
+	LOOP
+	PARAMETER
+	RETURN
+	THROW
+	TRAIT
+	TRY
+	VAR
+	VARIANT
+	WHILE
+)
+
+// Node is a node of the concrete syntax tree, it keeps every token and their
+// trivia so that the source prints back exactly
+type Node struct {
+	Kind     SyntaxKind
+	Token    tok.Token
+	Children []*Node
+}
+
+// treeBuilder collects the tokens consumed by the parser and groups them
+// into nodes as the grammar rules complete
+type treeBuilder struct {
+	elements []element
+}
+
+type element struct {
+	first int // index of the first token of the node
+	node  *Node
+}
+
+func (tb *treeBuilder) token(index int, t tok.Token) {
+	tb.elements = append(tb.elements, element{index, &Node{Kind: TOKEN, Token: t}})
+}
+
+// node wraps everything consumed since mark into a node of the given kind
+func (tb *treeBuilder) node(kind SyntaxKind, mark int) {
+	i := len(tb.elements)
+	for i > 0 && tb.elements[i-1].first >= mark {
+		i--
+	}
+
+	n := &Node{Kind: kind}
+	for _, e := range tb.elements[i:] {
+		n.Children = append(n.Children, e.node)
+	}
+	tb.elements = append(tb.elements[:i], element{mark, n})
+}
+
+// ParseTree parses the tokens of a program into a lossless tree, the tokens must come
+// with their trivia for the tree to print back the source. The tree is
+// returned along with the first error met.
+func (p *Parser) ParseTree() (*Node, error) {
+	p.tree = &treeBuilder{}
+	_, err := p.Program()
+
+	// Whatever was not parsed still belongs to the source
+	for !p.isAtEnd() {
+		p.advance()
+	}
+
+	root := &Node{Kind: ROOT}
+	for _, e := range p.tree.elements {
+		root.Children = append(root.Children, e.node)
+	}
+	root.Children = append(root.Children, &Node{Kind: TOKEN, Token: p.peek()})
+	p.tree = nil
+
+	return root, err
+}
+
+// String prints back the source of the node
+func (n *Node) String() string {
+	var buf bytes.Buffer
+	n.write(&buf)
+	return buf.String()
+}
+
+func (n *Node) write(buf *bytes.Buffer) {
+	if n.Kind == TOKEN {
+		for _, t := range n.Token.Leading {
+			buf.WriteString(t.Text)
+		}
+		buf.WriteString(n.Token.Lexeme)
+		for _, t := range n.Token.Trailing {
+			buf.WriteString(t.Text)
+		}
+		return
+	}
+
+	for _, c := range n.Children {
+		c.write(buf)
+	}
+}
+
+// Expr derives the AST of the node
+func (n *Node) Expr() (Expr, error) {
+	switch n.Kind {
+	case ROOT:
+		// The root ends with EOF
+		if len(n.Children) != 2 || n.Children[0].Kind != EXPRESSION {
+			return nil, fmt.Errorf("root holds %d nodes, expected an expression", len(n.Children)-1)
+		}
+		return n.Children[0].Children[0].Expr()
+	case ASSIGN:
+		value, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewAssign(n.Children[0].Children[0].Token, value), nil
+	case AWAIT:
+		value, err := n.Children[1].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewAwait(n.Children[0].Token, value), nil
+	case BINARY:
+		left, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		right, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewBinary(left, n.Children[1].Token, right), nil
+	case CALL:
+		callee, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		arguments := []Expr{}
+		for _, c := range n.Children[1:] {
+			if c.Kind == TOKEN {
+				continue
+			}
+			argument, err := c.Expr()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+		}
+		return NewCall(callee, n.Children[len(n.Children)-1].Token, arguments), nil
+	case COMPOUND:
+		target, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		value, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewCompound(target, n.Children[1].Token, value), nil
+	case DESTRUCTURE:
+		target, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		value, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		pattern, ok := destructure(target)
+		if !ok {
+			return nil, fmt.Errorf("cannot destructure into '%s'", n.Children[0])
+		}
+		return NewDestructure(pattern, n.Children[1].Token, value), nil
+	case GET:
+		object, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewGet(object, n.Children[2].Token), nil
+	case GROUPING:
+		expr, err := n.Children[1].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewGrouping(expr), nil
+	case INDEX:
+		object, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		index, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewIndex(object, n.Children[1].Token, index), nil
+	case INDEX_SET:
+		target, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		value, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		i, ok := target.(Index)
+		if !ok {
+			return nil, fmt.Errorf("cannot assign to '%s'", n.Children[0])
+		}
+		return NewIndexSet(i.object, i.bracket, i.index, value), nil
+	case LIST:
+		elements := []Expr{}
+		for _, c := range n.Children {
+			if c.Kind == TOKEN {
+				continue
+			}
+			element, err := c.Expr()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+		return NewListLiteral(elements), nil
+	case LITERAL:
+		return NewLiteral(literalValue(n.Children[0].Token)), nil
+	case MAP:
+		// Keys and values alternate between the punctuation
+		var entries []Expr
+		for _, c := range n.Children {
+			if c.Kind == TOKEN {
+				continue
+			}
+			entry, err := c.Expr()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+		keys, values := []Expr{}, []Expr{}
+		for i := 0; i+1 < len(entries); i += 2 {
+			keys = append(keys, entries[i])
+			values = append(values, entries[i+1])
+		}
+		return NewMapLiteral(n.Children[0].Token, keys, values), nil
+	case MATCH:
+		value, err := n.Children[1].Expr()
+		if err != nil {
+			return nil, err
+		}
+
+		// Arms start after the '{' and end on a ',' or the '}'
+		var patterns []Pattern
+		var guards, bodies []Expr
+		for i := 3; i < len(n.Children); i += 2 {
+			pattern, err := n.Children[i].Pattern()
+			if err != nil {
+				return nil, err
+			}
+			var guard Expr
+			if n.Children[i+1].Token.TokenType == tok.IF {
+				guard, err = n.Children[i+2].Expr()
+				if err != nil {
+					return nil, err
+				}
+				i += 2
+			}
+			body, err := n.Children[i+2].Expr()
+			if err != nil {
+				return nil, err
+			}
+			i += 2
+			patterns = append(patterns, pattern)
+			guards = append(guards, guard)
+			bodies = append(bodies, body)
+		}
+		return NewMatch(n.Children[0].Token, value, patterns, guards, bodies), nil
+	case NAMED:
+		value, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewNamed(n.Children[0].Children[0].Token, value), nil
+	case SET:
+		target, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		value, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		g, ok := target.(Get)
+		if !ok {
+			return nil, fmt.Errorf("cannot assign to '%s'", n.Children[0])
+		}
+		return NewSet(g.object, g.name, value), nil
+	case SLICE:
+		object, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		// Either bound may be missing, they are told apart by the ':'
+		var bounds [2]Expr
+		bound := 0
+		for _, c := range n.Children[2:] {
+			if c.Kind == TOKEN {
+				if c.Token.TokenType == tok.COLON {
+					bound++
+				}
+				continue
+			}
+			bounds[bound], err = c.Expr()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return NewSlice(object, n.Children[1].Token, bounds[0], bounds[1]), nil
+	case SPAWN:
+		call, err := n.Children[1].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewSpawn(n.Children[0].Token, call), nil
+	case SPREAD:
+		expr, err := n.Children[1].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewSpread(n.Children[0].Token, expr), nil
+	case THIS:
+		return NewThis(n.Children[0].Token), nil
+	case UNARY:
+		right, err := n.Children[1].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewUnary(n.Children[0].Token, right), nil
+	case UPDATE:
+		// The operator comes first when prefix
+		if n.Children[0].Kind == TOKEN {
+			target, err := n.Children[1].Expr()
+			if err != nil {
+				return nil, err
+			}
+			return NewUpdate(n.Children[0].Token, target, true), nil
+		}
+		target, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewUpdate(n.Children[1].Token, target, false), nil
+	case VARIABLE:
+		return NewVariable(n.Children[0].Token), nil
+	case YIELD:
+		if len(n.Children) == 1 {
+			return NewYield(n.Children[0].Token, nil), nil
+		}
+		value, err := n.Children[1].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewYield(n.Children[0].Token, value), nil
+	}
+
+	return nil, fmt.Errorf("'%s' is not an expression", n)
+}
+
+// Pattern derives the AST of a pattern node
+func (n *Node) Pattern() (Pattern, error) {
+	switch n.Kind {
+	case ALTERNATIVES:
+		alternatives, err := n.patterns()
+		if err != nil {
+			return nil, err
+		}
+		return NewAlternatives(alternatives), nil
+	case BINDING:
+		return NewBinding(n.Children[0].Token), nil
+	case CONSTANT:
+		return NewConstant(constantValue(n)), nil
+	case INSTANCE:
+		fields, err := n.patterns()
+		if err != nil {
+			return nil, err
+		}
+		return NewInstance(n.Children[0].Token, fields), nil
+	case MAPPING:
+		// Keys are constants with any pattern after their ':', or lone
+		// names binding the value under them
+		keys, values := []Expr{}, []Pattern{}
+		for i := 1; i < len(n.Children); i++ {
+			switch c := n.Children[i]; c.Kind {
+			case BINDING:
+				name := c.Children[0].Token
+				keys = append(keys, NewLiteral(name.Lexeme))
+				values = append(values, NewBinding(name))
+			case CONSTANT:
+				value, err := n.Children[i+2].Pattern()
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, NewLiteral(constantValue(c)))
+				values = append(values, value)
+				i += 2
+			}
+		}
+		return NewMapping(keys, values), nil
+	case REST:
+		return NewRest(n.Children[1].Token), nil
+	case SEQUENCE:
+		elements, err := n.patterns()
+		if err != nil {
+			return nil, err
+		}
+		return NewSequence(elements), nil
+	case WILDCARD:
+		return NewWildcard(n.Children[0].Token), nil
+	}
+
+	return nil, fmt.Errorf("'%s' is not a pattern", n)
+}
+
+// Stmts derives the AST of the program held by the root
+func (n *Node) Stmts() ([]Stmt, error) {
+	if n.Kind != ROOT {
+		return nil, fmt.Errorf("'%s' is not a program", n)
+	}
+
+	stmts := []Stmt{}
+	for _, c := range n.Children[:len(n.Children)-1] {
+		stmt, err := c.Stmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// Stmt derives the AST of a declaration node
+func (n *Node) Stmt() (Stmt, error) {
+	switch n.Kind {
+	case EXPRESSION:
+		expr, err := n.Children[0].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewExpression(expr), nil
+	case BLOCK:
+		stmts := []Stmt{}
+		for _, c := range n.Children[1 : len(n.Children)-1] {
+			stmt, err := c.Stmt()
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, stmt)
+		}
+		return NewBlock(stmts), nil
+	case BREAK, CONTINUE:
+		var label tok.Token
+		if len(n.Children) == 3 {
+			label = n.Children[1].Token
+		}
+		if n.Kind == BREAK {
+			return NewBreak(n.Children[0].Token, label), nil
+		}
+		return NewContinue(n.Children[0].Token, label), nil
+	case CLASS:
+		// Names after '<' and 'with' are the superclass and traits
+		var superclass tok.Token
+		var traits []tok.Token
+		var after tok.TokenType
+		for _, c := range n.Children[2:] {
+			switch c.Token.TokenType {
+			case tok.LESS, tok.WITH:
+				after = c.Token.TokenType
+			case tok.IDENTIFIER:
+				if after == tok.LESS {
+					superclass = c.Token
+				} else {
+					traits = append(traits, c.Token)
+				}
+			}
+		}
+		methods, err := n.methods()
+		if err != nil {
+			return nil, err
+		}
+		var fields []Field
+		for _, c := range n.Children {
+			if c.Kind != FIELD {
+				continue
+			}
+			field, err := c.field()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field)
+		}
+		return NewClass(n.Children[1].Token, superclass, traits, methods, fields), nil
+	case CONST:
+		initializer, err := n.Children[3].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewConst(n.Children[1].Token, initializer), nil
+	case ENUM:
+		var variants []tok.Token
+		var fields [][]tok.Token
+		for _, c := range n.Children {
+			if c.Kind != VARIANT {
+				continue
+			}
+			// A variant without parentheses has no payload at all
+			var payload []tok.Token
+			if len(c.Children) > 1 {
+				payload = []tok.Token{}
+			}
+			for _, f := range c.Children[1:] {
+				if f.Token.TokenType == tok.IDENTIFIER {
+					payload = append(payload, f.Token)
+				}
+			}
+			variants = append(variants, c.Children[0].Token)
+			fields = append(fields, payload)
+		}
+		return NewEnum(n.Children[1].Token, variants, fields), nil
+	case EXPORT:
+		declaration, err := n.Children[1].Stmt()
+		if err != nil {
+			return nil, err
+		}
+		return NewExport(n.Children[0].Token, declaration), nil
+	case FOR:
+		// for ( names in iterable ) body
+		var names []tok.Token
+		for _, c := range n.Children[2:] {
+			if c.Token.TokenType == tok.IN {
+				break
+			}
+			if c.Token.TokenType == tok.IDENTIFIER {
+				names = append(names, c.Token)
+			}
+		}
+		last := len(n.Children) - 1
+		iterable, err := n.Children[last-2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		body, err := n.Children[last].Stmt()
+		if err != nil {
+			return nil, err
+		}
+		return NewFor(n.Children[0].Token, names, iterable, body), nil
+	case FUNCTION:
+		return n.function()
+	case IMPORT:
+		// The path comes first, or last before the ';' after names
+		if n.Children[1].Token.TokenType == tok.STRING {
+			var alias tok.Token
+			if len(n.Children) == 5 {
+				alias = n.Children[3].Token
+			}
+			return NewImport(n.Children[0].Token, n.Children[1].Token, alias, nil), nil
+		}
+		names := []tok.Token{}
+		for _, c := range n.Children {
+			if c.Token.TokenType == tok.IDENTIFIER {
+				names = append(names, c.Token)
+			}
+		}
+		return NewImport(n.Children[0].Token, n.Children[len(n.Children)-2].Token, tok.Token{}, names), nil
+
+	case LABEL:
+		// The label was parsed as a variable before its ':' showed up
+		loop, err := n.Children[2].Stmt()
+		if err != nil {
+			return nil, err
+		}
+		return NewLabel(n.Children[0].Children[0].Token, loop), nil
+	case LOOP:
+		// for ( initializer condition? ; increment? ) body, a missing
+		// initializer leaves its ';' alone
+		children := n.Children[2:]
+		var initializer Stmt
+		if children[0].Token.TokenType != tok.SEMICOLON {
+			var err error
+			initializer, err = children[0].Stmt()
+			if err != nil {
+				return nil, err
+			}
+		}
+		children = children[1:]
+		var condition, increment Expr
+		if children[0].Token.TokenType != tok.SEMICOLON {
+			var err error
+			condition, err = children[0].Expr()
+			if err != nil {
+				return nil, err
+			}
+			children = children[1:]
+		}
+		children = children[1:]
+		if children[0].Token.TokenType != tok.RIGHT_PAREN {
+			var err error
+			increment, err = children[0].Expr()
+			if err != nil {
+				return nil, err
+			}
+			children = children[1:]
+		}
+		body, err := children[1].Stmt()
+		if err != nil {
+			return nil, err
+		}
+		return NewLoop(n.Children[0].Token, initializer, condition, increment, body), nil
+	case RETURN:
+		var value Expr
+		if len(n.Children) == 3 {
+			var err error
+			value, err = n.Children[1].Expr()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return NewReturn(n.Children[0].Token, value), nil
+	case THROW:
+		value, err := n.Children[1].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewThrow(n.Children[0].Token, value), nil
+	case TRAIT:
+		methods, err := n.methods()
+		if err != nil {
+			return nil, err
+		}
+		return NewTrait(n.Children[1].Token, methods), nil
+	case TRY:
+		body, err := n.Children[1].Stmt()
+		if err != nil {
+			return nil, err
+		}
+		var name tok.Token
+		var handler, finally Stmt
+		for i, c := range n.Children {
+			switch c.Token.TokenType {
+			case tok.CATCH:
+				name = n.Children[i+2].Token
+				handler, err = n.Children[i+4].Stmt()
+			case tok.FINALLY:
+				finally, err = n.Children[i+1].Stmt()
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return NewTry(n.Children[0].Token, body, name, handler, finally), nil
+	case VAR:
+		if n.Children[1].Kind != TOKEN {
+			pattern, err := n.Children[1].Pattern()
+			if err != nil {
+				return nil, err
+			}
+			initializer, err := n.Children[3].Expr()
+			if err != nil {
+				return nil, err
+			}
+			return NewVarPattern(n.Children[0].Token, pattern, initializer), nil
+		}
+		var annotation *Annotation
+		if n.Children[2].Token.TokenType == tok.COLON {
+			a := n.Children[3].annotation()
+			annotation = &a
+		}
+		initializer, err := n.Children[len(n.Children)-2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		return NewVar(n.Children[1].Token, annotation, initializer), nil
+	case WHILE:
+		condition, err := n.Children[2].Expr()
+		if err != nil {
+			return nil, err
+		}
+		body, err := n.Children[4].Stmt()
+		if err != nil {
+			return nil, err
+		}
+		return NewWhile(n.Children[0].Token, condition, body), nil
+	}
+
+	return nil, fmt.Errorf("'%s' is not a declaration", n)
+}
+
+// function derives the AST of a FUNCTION node, its parameters are the
+// PARAMETER nodes and an ANNOTATION after them is its return type
+func (n *Node) function() (Function, error) {
+	// Methods go without the 'fun' keyword
+	children := n.Children
+	static := children[0].Token.TokenType == tok.STATIC
+	if static {
+		children = children[1:]
+	}
+	async := children[0].Token.TokenType == tok.ASYNC
+	if async {
+		children = children[1:]
+	}
+	if children[0].Token.TokenType == tok.FUN {
+		children = children[1:]
+	}
+
+	// A setter's name follows 'set', a getter's is followed by its body
+	kind := FUNCTION_KIND
+	switch {
+	case children[1].Token.TokenType == tok.IDENTIFIER:
+		kind = SETTER_KIND
+		children = children[1:]
+	case children[1].Kind == BLOCK:
+		kind = GETTER_KIND
+	}
+
+	params := []Parameter{}
+	var returns *Annotation
+	for _, c := range children[2:] {
+		switch c.Kind {
+		case PARAMETER:
+			param, err := c.parameter()
+			if err != nil {
+				return Function{}, err
+			}
+			params = append(params, param)
+		case ANNOTATION:
+			a := c.annotation()
+			returns = &a
+		}
+	}
+
+	var body Stmt
+	if last := n.Children[len(n.Children)-1]; last.Kind == BLOCK {
+		var err error
+		body, err = last.Stmt()
+		if err != nil {
+			return Function{}, err
+		}
+	}
+	return NewFunction(children[0].Token, params, returns, body, async, static, kind), nil
+}
+
+// field derives the AST of a FIELD node, an expression among its children
+// is the initial value
+func (n *Node) field() (Field, error) {
+	children := n.Children
+	static := children[0].Token.TokenType == tok.STATIC
+	if static {
+		children = children[1:]
+	}
+
+	var annotation *Annotation
+	var value Expr
+	for _, c := range children[1:] {
+		switch c.Kind {
+		case TOKEN:
+		case ANNOTATION:
+			a := c.annotation()
+			annotation = &a
+		default:
+			var err error
+			value, err = c.Expr()
+			if err != nil {
+				return Field{}, err
+			}
+		}
+	}
+	return NewField(children[0].Token, annotation, value, static), nil
+}
+
+// parameter derives the AST of a PARAMETER node, an expression among its
+// children is the default value
+func (n *Node) parameter() (Parameter, error) {
+	children := n.Children
+	rest := children[0].Token.TokenType == tok.ELLIPSIS
+	if rest {
+		children = children[1:]
+	}
+
+	var annotation *Annotation
+	var value Expr
+	for _, c := range children[1:] {
+		switch c.Kind {
+		case TOKEN:
+		case ANNOTATION:
+			a := c.annotation()
+			annotation = &a
+		default:
+			var err error
+			value, err = c.Expr()
+			if err != nil {
+				return Parameter{}, err
+			}
+		}
+	}
+	return NewParameter(children[0].Token, annotation, value, rest), nil
+}
+
+// annotation derives the type held by an ANNOTATION node
+func (n *Node) annotation() Annotation {
+	var arguments []Annotation
+	for _, c := range n.Children {
+		if c.Kind == ANNOTATION {
+			arguments = append(arguments, c.annotation())
+		}
+	}
+	return NewAnnotation(n.Children[0].Token, arguments)
+}
+
+// methods derives the FUNCTION nodes among the children of the node
+func (n *Node) methods() ([]Function, error) {
+	methods := []Function{}
+	for _, c := range n.Children {
+		if c.Kind != FUNCTION {
+			continue
+		}
+		method, err := c.function()
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+	return methods, nil
+}
+
+// patterns derives the patterns among the children of the node
+func (n *Node) patterns() ([]Pattern, error) {
+	patterns := []Pattern{}
+	for _, c := range n.Children {
+		if c.Kind == TOKEN {
+			continue
+		}
+		pattern, err := c.Pattern()
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// constantValue returns the value of a CONSTANT node, negated when the
+// number has a '-'
+func constantValue(n *Node) interface{} {
+	if len(n.Children) == 2 {
+		value, _ := n.Children[1].Token.Literal.(float64)
+		return -value
+	}
+	return literalValue(n.Children[0].Token)
+}
+
+func literalValue(t tok.Token) interface{} {
+	switch t.TokenType {
+	case tok.FALSE:
+		return false
+	case tok.TRUE:
+		return true
+	case tok.NIL:
+		return nil
+	}
+	return t.Literal
+}
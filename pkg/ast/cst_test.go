@@ -0,0 +1,88 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/cedricmar/bazic/pkg/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTree(src string) (*Node, error) {
+	sc := scanner.NewScanner(src)
+	sc.KeepTrivia = true
+	p := NewSourceParser(&sc)
+	return p.ParseTree()
+}
+
+func TestParseTreePrintsBack(t *testing.T) {
+	srcs := []string{
+		"",
+		"1",
+		"# leading\n  -12 *  ( 3.5 + \"x\" ) # trailing\n== !true\t\r\n# the end",
+		"(1 + 2\n",
+		"1 2 @ \"unterminated",
+	}
+
+	for _, src := range srcs {
+		tree, _ := parseTree(src)
+		assert.Equal(t, src, tree.String())
+	}
+}
+
+func TestParseTreeStmts(t *testing.T) {
+	srcs := map[string]string{
+		"enum Shape {\n  Circle(r), # round\n  Rect(w, h),\n  Dot\n}": "(enum Shape (Circle r) (Rect w h) Dot)",
+		"import  \"lib/strings.bz\"  as str ; # strings":              "(import lib/strings.bz str)",
+		"import \"x.bz\";":                                                  "(import x.bz)",
+		"import {\n  a, b\n} from \"x.bz\";":                                "(import x.bz (a b))",
+		"export enum E { A(x) }":                                            "(export (enum E (A x)))",
+		"export  fun f ( a ) { return a ; } # exported":                     "(export (fun f (a) (block (return a))))",
+		"1 + 2; # first\nenum E { A }\n[3]":                                 "(+ 1 2)\n(enum E A)\n(list 3)",
+		"for ( k , v in m ) {\n  f(k); # key\n}":                            "(for (k v) m (block (call f k)))",
+		"for (x in [1, 2]) x;":                                              "(for (x) (list 1 2) x)",
+		"try {\n  throw  e ;\n} catch ( e ) {} # handled\nfinally { f(); }": "(try (block (throw e)) (catch e (block)) (finally (block (call f))))",
+		"var  m : Map < String , List<List<Number>> > = {} ; # typed":       "(var m Map<String, List<List<Number>>> (map))",
+		"const  c = 1 ; # fixed":                                            "(const c 1)",
+		"var x = 1;":                                                        "(var x 1)",
+		"var [ a , ...b ] = xs ; # split":                                   "(var (list a (... b)) xs)",
+		"var { name , \"n\" : [n] } = p;":                                   "(var (map (: name name) (: n (list n))) p)",
+		"trait T {\n  req(a , b); # required\n  m() { return ; }\n}":        "(trait T (fun req (a b)) (fun m () (block (return))))",
+		"class C < B with T , U { m(x) { return x; } }":                     "(class C (< B) (with T U) (fun m (x) (block (return x))))",
+		"outer : # label\nwhile (x) { break  outer ; continue; }":           "(label outer (while x (block (break outer) (continue))))",
+		"for ( var i = 0 ; i < 3 ; i++ ) # counted\n  f(i);":                "(loop (var i 0) (< i 3) (post++ i) (call f i))",
+		"for (;;) {}": "(loop () () () (block))",
+		"fun  f ( a : String , b: List< Number > ) : Bool { return a ; }":                                                       "(fun f (a:String b:List<Number>) (-> Bool) (block (return a)))",
+		"trait T { m(x : Number) : String ; }":                                                                                  "(trait T (fun m (x:Number) (-> String)))",
+		"fun greet ( name , greeting = \"hi\" , ... rest ) {}":                                                                  "(fun greet (name greeting=hi ...rest) (block))",
+		"async  fun f() {} # later\ntrait T { async m() ; }":                                                                    "(async fun f () (block))\n(trait T (async fun m ()))",
+		"class C {\n  static  n = 1 ; # shared\n  w : Number ;\n  area { return w ; }\n  set area ( v ) {}\n  static m() {}\n}": "(class C (static field n 1) (field w Number) (get area (block (return w))) (set area (v) (block)) (static fun m () (block)))",
+		"for (x = 1 ; ; ) {}":                    "(loop (assign x 1) () () (block))",
+		"for (; x ;x--) {}":                      "(loop () x (post-- x) (block))",
+		"{ # scoped\n  x = 1 ;\n}\n{\"k\": x} ;": "(block (assign x 1))\n(map (: k x))",
+	}
+
+	for src, want := range srcs {
+		tree, err := parseTree(src)
+		assert.Nil(t, err, src)
+		assert.Equal(t, src, tree.String())
+
+		stmts, err := tree.Stmts()
+		assert.Nil(t, err, src)
+		assert.Equal(t, want, printProgram(stmts), src)
+	}
+}
+
+func TestParseTreeExpr(t *testing.T) {
+	src := "# leading\n  -12 *  ( 3.5 ** \"x\" ) # trailing\n== !true // ~2"
+
+	tree, err := parseTree(src)
+	assert.Nil(t, err)
+
+	expr, err := tree.Expr()
+	assert.Nil(t, err)
+
+	sc := scanner.NewScanner(src)
+	p := NewParser(sc.ScanTokens())
+	want, _ := p.Parse()
+	assert.Equal(t, NewPrinter().Print(want), NewPrinter().Print(expr))
+}
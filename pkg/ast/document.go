@@ -0,0 +1,184 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/cedricmar/bazic/pkg/scanner"
+
+	tok "github.com/cedricmar/bazic/pkg/token"
+)
+
+// Edit replaces the source between the byte offsets Start and End by Text
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Document keeps the tokens and AST of a source up to date as it is edited,
+// for editors to analyse it on every keystroke. An edit only scans again the
+// tokens it touches, and only parses again the top-level declarations that
+// read them. The other declarations are reused as they are, so are the
+// groupings the edit left alone within those parsed again.
+type Document struct {
+	source       string
+	tokens       []tok.Token
+	offsets      []int // where each token starts in the source
+	groupings    map[int]grouping
+	declarations map[int]declaration
+	stmts        []Stmt
+	err          error
+}
+
+// grouping is a parsed grouping, the number of tokens it spans and how
+// deep it nests
+type grouping struct {
+	expr   Expr
+	tokens int
+	depth  int
+}
+
+// declaration is a parsed top-level declaration, the number of tokens it
+// spans and the number the parser read for it, looked ahead ones included
+type declaration struct {
+	stmt   Stmt
+	tokens int
+	reach  int
+}
+
+func NewDocument(source string) *Document {
+	d := &Document{
+		source:       source,
+		groupings:    map[int]grouping{},
+		declarations: map[int]declaration{},
+	}
+	d.tokens, d.offsets = scan(source)
+	d.parse()
+	return d
+}
+
+func (d *Document) Source() string {
+	return d.source
+}
+
+func (d *Document) Tokens() []tok.Token {
+	return d.tokens
+}
+
+// Offsets returns the byte offset of each token in the source
+func (d *Document) Offsets() []int {
+	return d.offsets
+}
+
+// Stmts returns the AST of the program, along with the error that stopped
+// parsing it if any
+func (d *Document) Stmts() ([]Stmt, error) {
+	return d.stmts, d.err
+}
+
+// Apply edits the source then brings tokens and AST up to date
+func (d *Document) Apply(e Edit) error {
+	if e.Start < 0 || e.Start > e.End || e.End > len(d.source) {
+		return fmt.Errorf("edit [%d, %d) is out of the source [0, %d)", e.Start, e.End, len(d.source))
+	}
+
+	d.source = d.source[:e.Start] + e.Text + d.source[e.End:]
+	delta := len(e.Text) - (e.End - e.Start)
+
+	// Tokens are scanned again from the first one the edit can change, the
+	// scanner looks up to two bytes past a number for its decimals
+	first := 0
+	for first < len(d.tokens)-1 && d.offsets[first]+len(d.tokens[first].Lexeme)+1 < e.Start {
+		first++
+	}
+	from, line := 0, 1
+	if first > 0 {
+		from = d.offsets[first-1] + len(d.tokens[first-1].Lexeme)
+		line = d.tokens[first-1].Line
+	}
+
+	sc := scanner.NewScannerAt(d.source[from:], line, from)
+	var tokens []tok.Token
+	var offsets []int
+	last, lineDelta := first, 0
+	for {
+		t := sc.NextToken()
+		offset := sc.Offset()
+
+		// Past the edit, once a token starts where an old one did the rest
+		// of the tokens are the old ones
+		for last < len(d.tokens)-1 && d.offsets[last]+delta < offset {
+			last++
+		}
+		if d.offsets[last] >= e.End && d.offsets[last]+delta == offset {
+			lineDelta = t.Line - d.tokens[last].Line
+			break
+		}
+
+		tokens = append(tokens, t)
+		offsets = append(offsets, offset)
+		if t.TokenType == tok.EOF {
+			last = len(d.tokens)
+			break
+		}
+	}
+
+	d.relocate(first, last, len(tokens), lineDelta)
+
+	for i := last; i < len(d.tokens); i++ {
+		d.tokens[i].Line += lineDelta
+		d.offsets[i] += delta
+	}
+	d.tokens = append(append(d.tokens[:first:first], tokens...), d.tokens[last:]...)
+	d.offsets = append(append(d.offsets[:first:first], offsets...), d.offsets[last:]...)
+
+	d.parse()
+	return nil
+}
+
+// relocate keeps the groupings and declarations untouched by scanning the
+// tokens [first, last) again, those after them are shifted to their new index
+func (d *Document) relocate(first, last, scanned, lineDelta int) {
+	groupings := map[int]grouping{}
+	for i, g := range d.groupings {
+		if i+g.tokens <= first {
+			groupings[i] = g
+		} else if i >= last && lineDelta == 0 {
+			groupings[i+scanned-(last-first)] = g
+		}
+	}
+	d.groupings = groupings
+
+	// A declaration also depends on the tokens looked at past its end
+	declarations := map[int]declaration{}
+	for i, decl := range d.declarations {
+		if i+decl.reach <= first {
+			declarations[i] = decl
+		} else if i >= last && lineDelta == 0 {
+			declarations[i+scanned-(last-first)] = decl
+		}
+	}
+	d.declarations = declarations
+}
+
+func (d *Document) parse() {
+	p := NewParser(d.tokens)
+	p.groupings = d.groupings
+	p.declarations = d.declarations
+	d.stmts, d.err = p.Program()
+}
+
+// scan returns the tokens of source and where they start
+func scan(source string) ([]tok.Token, []int) {
+	sc := scanner.NewScanner(source)
+	var tokens []tok.Token
+	var offsets []int
+	for {
+		t := sc.NextToken()
+		tokens = append(tokens, t)
+		offsets = append(offsets, sc.Offset())
+		if t.TokenType == tok.EOF {
+			return tokens, offsets
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package ast
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentRandomEdits(t *testing.T) {
+	pieces := []string{
+		"1", "23", ".", "4.5", "(", ")", "+", "-", "*", "/", "!", "=", "<", ">",
+		" ", "\n", "\"", "ab", "true", "nil", "# c",
+		"%", "&", "|", "^", "~", "#", ";", "enum E { A }",
+		"for (x in y) ", "while (x) ", "{", "}", "break;",
+		"class C { m() ", "trait T { m(); }", "var x: List<List<N>> = ", "export ", "fun f() ",
+		"var a = 1; ", "import { a } from \"m\"; ", "a = b; ",
+	}
+	r := rand.New(rand.NewSource(42))
+	d := NewDocument("(1 + 2) * (3 - (4 / 5.5))\n== !(true) # end")
+
+	for i := 0; i < 5000; i++ {
+		start := r.Intn(len(d.Source()) + 1)
+		end := start + r.Intn(len(d.Source())-start+1)%4
+		text := ""
+		for n := r.Intn(4); n > 0; n-- {
+			text += pieces[r.Intn(len(pieces))]
+		}
+		assert.Nil(t, d.Apply(Edit{start, end, text}))
+
+		tokens, offsets := scan(d.Source())
+		p := NewParser(tokens)
+		stmts, err := p.Program()
+		gotStmts, gotErr := d.Stmts()
+
+		if !assert.Equal(t, tokens, d.Tokens(), d.Source()) ||
+			!assert.Equal(t, offsets, d.Offsets(), d.Source()) ||
+			!assert.Equal(t, stmts, gotStmts, d.Source()) ||
+			!assert.Equal(t, err, gotErr, d.Source()) {
+			return
+		}
+	}
+}
+
+func TestDocumentReusesGroupings(t *testing.T) {
+	d := NewDocument("(1 + 2) * 3")
+	d.groupings[0] = grouping{NewLiteral("reused"), 5, 1}
+
+	assert.Nil(t, d.Apply(Edit{10, 11, "42"}))
+	stmts, err := d.Stmts()
+	assert.Nil(t, err)
+	assert.Equal(t, "(* reused 42)", printProgram(stmts))
+
+	assert.Nil(t, d.Apply(Edit{1, 2, "7"}))
+	stmts, _ = d.Stmts()
+	assert.Equal(t, "(* (group (+ 7 2)) 42)", printProgram(stmts))
+}
+
+func TestDocumentReusesDeclarations(t *testing.T) {
+	d := NewDocument("var a = 1; var b = 2; var c = 3;")
+	assert.Equal(t, 6, d.declarations[5].reach)
+	d.declarations[5] = declaration{NewExpression(NewLiteral("reused")), 5, 6}
+
+	// Edits on either side leave it alone
+	assert.Nil(t, d.Apply(Edit{30, 31, "42"}))
+	assert.Nil(t, d.Apply(Edit{8, 9, "7"}))
+	stmts, err := d.Stmts()
+	assert.Nil(t, err)
+	assert.Equal(t, "(var a 7)\nreused\n(var c 42)", printProgram(stmts))
+
+	// Editing it parses it again
+	assert.Nil(t, d.Apply(Edit{19, 20, "5"}))
+	stmts, _ = d.Stmts()
+	assert.Equal(t, "(var a 7)\n(var b 5)\n(var c 42)", printProgram(stmts))
+}
+
+func TestDocumentReuseKeepsDepthLimit(t *testing.T) {
+	src := strings.Repeat("(", 200) + "1" + strings.Repeat(")", 200)
+	d := NewDocument(src)
+	_, err := d.Stmts()
+	assert.Nil(t, err)
+
+	// The groupings now sit too deep to be reused
+	assert.Nil(t, d.Apply(Edit{0, 0, strings.Repeat("! ", 100)}))
+	_, err = d.Stmts()
+	_, want := program(d.Source())
+	assert.IsType(t, ParseError{}, want)
+	assert.Equal(t, want, err)
+
+	// And are fine again once the edit is undone
+	assert.Nil(t, d.Apply(Edit{0, 200, ""}))
+	_, err = d.Stmts()
+	assert.Nil(t, err)
+}
+
+func TestDocumentEditOutOfRange(t *testing.T) {
+	d := NewDocument("1 + 2")
+	assert.NotNil(t, d.Apply(Edit{3, 6, ""}))
+	assert.NotNil(t, d.Apply(Edit{2, 1, ""}))
+}
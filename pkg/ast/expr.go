@@ -13,10 +13,66 @@ type Accepter interface {
 
 // Visitor allows to add features to Types
 type Visitor interface {
+	VisitAssignExpr(expr Assign) interface{}
+	VisitAwaitExpr(expr Await) interface{}
 	VisitBinaryExpr(expr Binary) interface{}
+	VisitCallExpr(expr Call) interface{}
+	VisitCompoundExpr(expr Compound) interface{}
+	VisitDestructureExpr(expr Destructure) interface{}
+	VisitGetExpr(expr Get) interface{}
 	VisitGroupingExpr(expr Grouping) interface{}
+	VisitIndexExpr(expr Index) interface{}
+	VisitIndexSetExpr(expr IndexSet) interface{}
+	VisitListLiteralExpr(expr ListLiteral) interface{}
 	VisitLiteralExpr(expr Literal) interface{}
+	VisitMapLiteralExpr(expr MapLiteral) interface{}
+	VisitMatchExpr(expr Match) interface{}
+	VisitNamedExpr(expr Named) interface{}
+	VisitSetExpr(expr Set) interface{}
+	VisitSliceExpr(expr Slice) interface{}
+	VisitSpawnExpr(expr Spawn) interface{}
+	VisitSpreadExpr(expr Spread) interface{}
+	VisitThisExpr(expr This) interface{}
 	VisitUnaryExpr(expr Unary) interface{}
+	VisitUpdateExpr(expr Update) interface{}
+	VisitVariableExpr(expr Variable) interface{}
+	VisitYieldExpr(expr Yield) interface{}
+}
+
+// Assign is a node of the AST
+type Assign struct {
+	name  tok.Token
+	value Expr
+}
+
+// NewAssign returns a new node of type Assign
+func NewAssign(name tok.Token, value Expr) Assign {
+	return Assign{
+		name:  name,
+		value: value,
+	}
+}
+
+func (a Assign) Accept(v Visitor) interface{} {
+	return v.VisitAssignExpr(a)
+}
+
+// Await is a node of the AST
+type Await struct {
+	keyword tok.Token
+	value   Expr
+}
+
+// NewAwait returns a new node of type Await
+func NewAwait(keyword tok.Token, value Expr) Await {
+	return Await{
+		keyword: keyword,
+		value:   value,
+	}
+}
+
+func (a Await) Accept(v Visitor) interface{} {
+	return v.VisitAwaitExpr(a)
 }
 
 // Binary is a node of the AST
@@ -39,6 +95,84 @@ func (b Binary) Accept(v Visitor) interface{} {
 	return v.VisitBinaryExpr(b)
 }
 
+// Call is a node of the AST
+type Call struct {
+	callee    Expr
+	paren     tok.Token
+	arguments []Expr
+}
+
+// NewCall returns a new node of type Call
+func NewCall(callee Expr, paren tok.Token, arguments []Expr) Call {
+	return Call{
+		callee:    callee,
+		paren:     paren,
+		arguments: arguments,
+	}
+}
+
+func (c Call) Accept(v Visitor) interface{} {
+	return v.VisitCallExpr(c)
+}
+
+// Compound is a node of the AST
+type Compound struct {
+	target   Expr
+	operator tok.Token
+	value    Expr
+}
+
+// NewCompound returns a new node of type Compound
+func NewCompound(target Expr, operator tok.Token, value Expr) Compound {
+	return Compound{
+		target:   target,
+		operator: operator,
+		value:    value,
+	}
+}
+
+func (c Compound) Accept(v Visitor) interface{} {
+	return v.VisitCompoundExpr(c)
+}
+
+// Destructure is a node of the AST
+type Destructure struct {
+	pattern Pattern
+	equals  tok.Token
+	value   Expr
+}
+
+// NewDestructure returns a new node of type Destructure
+func NewDestructure(pattern Pattern, equals tok.Token, value Expr) Destructure {
+	return Destructure{
+		pattern: pattern,
+		equals:  equals,
+		value:   value,
+	}
+}
+
+func (d Destructure) Accept(v Visitor) interface{} {
+	return v.VisitDestructureExpr(d)
+}
+
+// Get is a node of the AST
+type Get struct {
+	object Expr
+	name   tok.Token
+}
+
+// NewGet returns a new node of type Get
+func NewGet(object Expr, name tok.Token) Get {
+	return Get{
+		object: object,
+		name:   name,
+	}
+}
+
+func (g Get) Accept(v Visitor) interface{} {
+	return v.VisitGetExpr(g)
+}
+
 // Grouping is a node of the AST
 type Grouping struct {
 	expression Expr
@@ -55,6 +189,64 @@ func (g Grouping) Accept(v Visitor) interface{} {
 	return v.VisitGroupingExpr(g)
 }
 
+// Index is a node of the AST
+type Index struct {
+	object  Expr
+	bracket tok.Token
+	index   Expr
+}
+
+// NewIndex returns a new node of type Index
+func NewIndex(object Expr, bracket tok.Token, index Expr) Index {
+	return Index{
+		object:  object,
+		bracket: bracket,
+		index:   index,
+	}
+}
+
+func (i Index) Accept(v Visitor) interface{} {
+	return v.VisitIndexExpr(i)
+}
+
+// IndexSet is a node of the AST
+type IndexSet struct {
+	object  Expr
+	bracket tok.Token
+	index   Expr
+	value   Expr
+}
+
+// NewIndexSet returns a new node of type IndexSet
+func NewIndexSet(object Expr, bracket tok.Token, index Expr, value Expr) IndexSet {
+	return IndexSet{
+		object:  object,
+		bracket: bracket,
+		index:   index,
+		value:   value,
+	}
+}
+
+func (i IndexSet) Accept(v Visitor) interface{} {
+	return v.VisitIndexSetExpr(i)
+}
+
+// ListLiteral is a node of the AST
+type ListLiteral struct {
+	elements []Expr
+}
+
+// NewListLiteral returns a new node of type ListLiteral
+func NewListLiteral(elements []Expr) ListLiteral {
+	return ListLiteral{
+		elements: elements,
+	}
+}
+
+func (l ListLiteral) Accept(v Visitor) interface{} {
+	return v.VisitListLiteralExpr(l)
+}
+
 // Literal is a node of the AST
 type Literal struct {
 	value interface{}
@@ -71,6 +263,162 @@ func (l Literal) Accept(v Visitor) interface{} {
 	return v.VisitLiteralExpr(l)
 }
 
+// MapLiteral is a node of the AST
+type MapLiteral struct {
+	brace  tok.Token
+	keys   []Expr
+	values []Expr
+}
+
+// NewMapLiteral returns a new node of type MapLiteral
+func NewMapLiteral(brace tok.Token, keys []Expr, values []Expr) MapLiteral {
+	return MapLiteral{
+		brace:  brace,
+		keys:   keys,
+		values: values,
+	}
+}
+
+func (m MapLiteral) Accept(v Visitor) interface{} {
+	return v.VisitMapLiteralExpr(m)
+}
+
+// Match is a node of the AST
+type Match struct {
+	keyword  tok.Token
+	value    Expr
+	patterns []Pattern
+	guards   []Expr
+	bodies   []Expr
+}
+
+// NewMatch returns a new node of type Match
+func NewMatch(keyword tok.Token, value Expr, patterns []Pattern, guards []Expr, bodies []Expr) Match {
+	return Match{
+		keyword:  keyword,
+		value:    value,
+		patterns: patterns,
+		guards:   guards,
+		bodies:   bodies,
+	}
+}
+
+func (m Match) Accept(v Visitor) interface{} {
+	return v.VisitMatchExpr(m)
+}
+
+// Named is a node of the AST
+type Named struct {
+	name  tok.Token
+	value Expr
+}
+
+// NewNamed returns a new node of type Named
+func NewNamed(name tok.Token, value Expr) Named {
+	return Named{
+		name:  name,
+		value: value,
+	}
+}
+
+func (n Named) Accept(v Visitor) interface{} {
+	return v.VisitNamedExpr(n)
+}
+
+// Set is a node of the AST
+type Set struct {
+	object Expr
+	name   tok.Token
+	value  Expr
+}
+
+// NewSet returns a new node of type Set
+func NewSet(object Expr, name tok.Token, value Expr) Set {
+	return Set{
+		object: object,
+		name:   name,
+		value:  value,
+	}
+}
+
+func (s Set) Accept(v Visitor) interface{} {
+	return v.VisitSetExpr(s)
+}
+
+// Slice is a node of the AST
+type Slice struct {
+	object  Expr
+	bracket tok.Token
+	start   Expr
+	end     Expr
+}
+
+// NewSlice returns a new node of type Slice
+func NewSlice(object Expr, bracket tok.Token, start Expr, end Expr) Slice {
+	return Slice{
+		object:  object,
+		bracket: bracket,
+		start:   start,
+		end:     end,
+	}
+}
+
+func (s Slice) Accept(v Visitor) interface{} {
+	return v.VisitSliceExpr(s)
+}
+
+// Spawn is a node of the AST
+type Spawn struct {
+	keyword tok.Token
+	call    Expr
+}
+
+// NewSpawn returns a new node of type Spawn
+func NewSpawn(keyword tok.Token, call Expr) Spawn {
+	return Spawn{
+		keyword: keyword,
+		call:    call,
+	}
+}
+
+func (s Spawn) Accept(v Visitor) interface{} {
+	return v.VisitSpawnExpr(s)
+}
+
+// Spread is a node of the AST
+type Spread struct {
+	ellipsis   tok.Token
+	expression Expr
+}
+
+// NewSpread returns a new node of type Spread
+func NewSpread(ellipsis tok.Token, expression Expr) Spread {
+	return Spread{
+		ellipsis:   ellipsis,
+		expression: expression,
+	}
+}
+
+func (s Spread) Accept(v Visitor) interface{} {
+	return v.VisitSpreadExpr(s)
+}
+
+// This is a node of the AST
+type This struct {
+	keyword tok.Token
+}
+
+// NewThis returns a new node of type This
+func NewThis(keyword tok.Token) This {
+	return This{
+		keyword: keyword,
+	}
+}
+
+func (t This) Accept(v Visitor) interface{} {
+	return v.VisitThisExpr(t)
+}
+
 // Unary is a node of the AST
 type Unary struct {
 	operator tok.Token
@@ -88,3 +436,57 @@ func NewUnary(operator tok.Token, right Expr) Unary {
 func (u Unary) Accept(v Visitor) interface{} {
 	return v.VisitUnaryExpr(u)
 }
+
+// Update is a node of the AST
+type Update struct {
+	operator tok.Token
+	target   Expr
+	prefix   bool
+}
+
+// NewUpdate returns a new node of type Update
+func NewUpdate(operator tok.Token, target Expr, prefix bool) Update {
+	return Update{
+		operator: operator,
+		target:   target,
+		prefix:   prefix,
+	}
+}
+
+func (u Update) Accept(v Visitor) interface{} {
+	return v.VisitUpdateExpr(u)
+}
+
+// Variable is a node of the AST
+type Variable struct {
+	name tok.Token
+}
+
+// NewVariable returns a new node of type Variable
+func NewVariable(name tok.Token) Variable {
+	return Variable{
+		name: name,
+	}
+}
+
+func (va Variable) Accept(v Visitor) interface{} {
+	return v.VisitVariableExpr(va)
+}
+
+// Yield is a node of the AST
+type Yield struct {
+	keyword tok.Token
+	value   Expr
+}
+
+// NewYield returns a new node of type Yield
+func NewYield(keyword tok.Token, value Expr) Yield {
+	return Yield{
+		keyword: keyword,
+		value:   value,
+	}
+}
+
+func (y Yield) Accept(v Visitor) interface{} {
+	return v.VisitYieldExpr(y)
+}
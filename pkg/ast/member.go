@@ -0,0 +1,30 @@
+package ast
+
+import (
+	tok "github.com/cedricmar/bazic/pkg/token"
+)
+
+// FunctionKind tells plain functions and methods from the property
+// accessors of a class
+type FunctionKind int
+
+const (
+	FUNCTION_KIND FunctionKind = iota
+	// A getter is declared without parentheses, as in area { ... }
+	GETTER_KIND
+	// A setter takes the value assigned, as in set area(value) { ... }
+	SETTER_KIND
+)
+
+// Field is a field declared in a class body, with its type when annotated
+// and its initial value. A static field belongs to the class object.
+type Field struct {
+	name       tok.Token
+	annotation *Annotation
+	value      Expr
+	static     bool
+}
+
+func NewField(name tok.Token, annotation *Annotation, value Expr, static bool) Field {
+	return Field{name: name, annotation: annotation, value: value, static: static}
+}
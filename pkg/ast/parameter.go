@@ -0,0 +1,19 @@
+package ast
+
+import (
+	tok "github.com/cedricmar/bazic/pkg/token"
+)
+
+// Parameter is a parameter of a function, with its type when annotated and
+// the value it takes when no argument is given. A rest parameter collects
+// the arguments left in a list.
+type Parameter struct {
+	name       tok.Token
+	annotation *Annotation
+	value      Expr
+	rest       bool
+}
+
+func NewParameter(name tok.Token, annotation *Annotation, value Expr, rest bool) Parameter {
+	return Parameter{name: name, annotation: annotation, value: value, rest: rest}
+}
@@ -8,118 +8,2150 @@ import (
 
 // Parser uses Recursive Descent Parsing
 type Parser struct {
+	source        TokenSource
+	currentToken  tok.Token
+	previousToken tok.Token
+	consumed      int
+	depth         int
+	deepest       int // deepest nesting reached, for groupings to record theirs
+	// Labels of the enclosing loops, empty for unlabelled ones
+	loops []string
+	// Number of enclosing function bodies
+	functions int
+	// Number of '>' of a '>>' still to close type arguments
+	split int
+	// Tokens pulled from the source past the current one, by lookAhead
+	ahead []tok.Token
+	// Only set while building a lossless tree
+	tree *treeBuilder
+	// Only set when parsing a Document again, by index of their '('
+	groupings map[int]grouping
+	// Only set when parsing a Document again, by index of their first token
+	declarations map[int]declaration
+}
+
+// maxDepth bounds the nesting of expressions, deeper ones are errors rather
+// than exhausting the stack
+const maxDepth = 256
+
+// TokenSource hands out tokens one at a time, ending with an EOF token
+type TokenSource interface {
+	NextToken() tok.Token
+}
+
+type ParseError struct {
+	token tok.Token
+	msg   string
+}
+
+// tokenSlice is a TokenSource over already scanned tokens
+type tokenSlice struct {
 	tokens  []tok.Token
 	current int
 }
 
-type ParseError struct {
-	token tok.Token
-	msg   string
+func (ts *tokenSlice) NextToken() tok.Token {
+	// Tokens not ending with EOF end anyway
+	if ts.current >= len(ts.tokens) {
+		eof := tok.Token{TokenType: tok.EOF, Line: 1}
+		if len(ts.tokens) > 0 {
+			eof.Line = ts.tokens[len(ts.tokens)-1].Line
+		}
+		return eof
+	}
+	ts.current++
+	return ts.tokens[ts.current-1]
+}
+
+func NewParser(tokens []tok.Token) Parser {
+	return NewSourceParser(&tokenSlice{tokens, 0})
+}
+
+// NewSourceParser returns a Parser pulling its tokens lazily from src
+func NewSourceParser(src TokenSource) Parser {
+	return Parser{
+		source:       src,
+		currentToken: src.NextToken(),
+	}
+}
+
+func (p *Parser) Parse() (Expr, error) {
+	expr, err := p.Expression()
+	if err != nil {
+		return expr, err
+	}
+
+	// Nothing may follow the expression
+	if !p.isAtEnd() {
+		return expr, ParseError{
+			token: p.peek(),
+			msg:   "Expect end of expression.",
+		}
+	}
+
+	return expr, nil
+}
+
+func (e ParseError) Error() string {
+	scanner.Error(e.token, e.msg)
+	return ""
+}
+
+// program        → declaration* EOF
+func (p *Parser) Program() ([]Stmt, error) {
+	stmts := []Stmt{}
+	for !p.isAtEnd() {
+		mark := p.mark()
+		if d, found := p.declarations[mark]; found {
+			for i := 0; i < d.tokens; i++ {
+				p.advance()
+			}
+			stmts = append(stmts, d.stmt)
+			continue
+		}
+		stmt, err := p.Declaration()
+		if err != nil {
+			return stmts, err
+		}
+		if p.declarations != nil {
+			p.declarations[mark] = declaration{stmt, p.consumed - mark, p.pulled() - mark}
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// declaration    → "export"? exportable | importDecl | statement
+// exportable     → classDecl | constDecl | enumDecl | funDecl | traitDecl | varDecl
+func (p *Parser) Declaration() (Stmt, error) {
+	mark := p.mark()
+	if p.match(tok.EXPORT) {
+		keyword := p.previous()
+		// Only a module's own top level is visible to its importers
+		if p.depth > 0 {
+			return nil, ParseError{
+				token: keyword,
+				msg:   "Can only export from the top level.",
+			}
+		}
+		switch p.peek().TokenType {
+		case tok.ASYNC, tok.CLASS, tok.CONST, tok.ENUM, tok.FUN, tok.TRAIT, tok.VAR:
+		default:
+			return nil, ParseError{
+				token: p.peek(),
+				msg:   "Expect declaration after 'export'.",
+			}
+		}
+		declaration, err := p.Declaration()
+		if err != nil {
+			return nil, err
+		}
+		p.node(EXPORT, mark)
+		return NewExport(keyword, declaration), nil
+	}
+
+	if p.match(tok.ENUM) {
+		return p.enum(mark)
+	}
+
+	if p.match(tok.IMPORT) {
+		return p.importDecl(mark)
+	}
+
+	if p.match(tok.CLASS) {
+		return p.class(mark)
+	}
+
+	if p.match(tok.TRAIT) {
+		return p.trait(mark)
+	}
+
+	if p.match(tok.VAR) {
+		return p.varDeclaration(mark)
+	}
+
+	if p.match(tok.CONST) {
+		return p.constDeclaration(mark)
+	}
+
+	if p.match(tok.ASYNC) {
+		_, err := p.consume(tok.FUN, "Expect 'fun' after 'async'.")
+		if err != nil {
+			return nil, err
+		}
+		return p.function(mark, true, false)
+	}
+
+	if p.match(tok.FUN) {
+		return p.function(mark, false, false)
+	}
+
+	return p.statement()
+}
+
+// statement      → block | forStmt | whileStmt | breakStmt | continueStmt | returnStmt | throwStmt | tryStmt | labelled | exprStmt
+// labelled       → IDENTIFIER ":" ( forStmt | whileStmt )
+func (p *Parser) statement() (Stmt, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	if p.check(tok.LEFT_BRACE) && !p.mapAhead() {
+		return p.block()
+	}
+
+	mark := p.mark()
+	if p.match(tok.FOR) {
+		return p.forStatement(mark, "")
+	}
+	if p.match(tok.WHILE) {
+		return p.whileStatement(mark, "")
+	}
+	if p.match(tok.BREAK, tok.CONTINUE) {
+		return p.jump(mark)
+	}
+	if p.match(tok.RETURN) {
+		return p.returnStatement(mark)
+	}
+	if p.match(tok.THROW) {
+		return p.throw(mark)
+	}
+	if p.match(tok.TRY) {
+		return p.try(mark)
+	}
+
+	expr, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	// A label is told apart from an expression by its ':'
+	if v, ok := expr.(Variable); ok && p.match(tok.COLON) {
+		return p.labelled(mark, v.name)
+	}
+	return p.expressionStatement(mark, expr)
+}
+
+// exprStmt       → expression ( ";" | EOF )
+func (p *Parser) expressionStatement(mark int, expr Expr) (Stmt, error) {
+	// The last expression of a program may go without its ';'
+	if !p.isAtEnd() {
+		_, err := p.consume(tok.SEMICOLON, "Expect ';' after expression.")
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.node(EXPRESSION, mark)
+	return NewExpression(expr), nil
+}
+
+func (p *Parser) labelled(mark int, name tok.Token) (Stmt, error) {
+	if p.inLoop(name.Lexeme) {
+		return nil, ParseError{
+			token: name,
+			msg:   "Label already used by an enclosing loop.",
+		}
+	}
+
+	loopMark := p.mark()
+	var loop Stmt
+	var err error
+	switch {
+	case p.match(tok.FOR):
+		loop, err = p.forStatement(loopMark, name.Lexeme)
+	case p.match(tok.WHILE):
+		loop, err = p.whileStatement(loopMark, name.Lexeme)
+	default:
+		return nil, ParseError{
+			token: p.peek(),
+			msg:   "Expect loop after label.",
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.node(LABEL, mark)
+	return NewLabel(name, loop), nil
+}
+
+// forStmt        → "for" "(" ( forIn | forStep ) ")" body
+// forIn          → IDENTIFIER ( "," IDENTIFIER )? "in" expression
+// forStep        → ( varDecl | exprStmt | ";" ) expression? ";" expression?
+func (p *Parser) forStatement(mark int, label string) (Stmt, error) {
+	keyword := p.previous()
+	_, err := p.consume(tok.LEFT_PAREN, "Expect '(' after 'for'.")
+	if err != nil {
+		return nil, err
+	}
+	if !p.forInAhead() {
+		return p.forStep(mark, keyword, label)
+	}
+
+	name, err := p.consume(tok.IDENTIFIER, "Expect loop variable name.")
+	if err != nil {
+		return nil, err
+	}
+	names := []tok.Token{name}
+	if p.match(tok.COMMA) {
+		name, err = p.consume(tok.IDENTIFIER, "Expect loop variable name.")
+		if err != nil {
+			return nil, err
+		}
+		if name.Lexeme == names[0].Lexeme {
+			return nil, ParseError{
+				token: name,
+				msg:   "Duplicate loop variable name.",
+			}
+		}
+		names = append(names, name)
+	}
+
+	_, err = p.consume(tok.IN, "Expect 'in' after loop variables.")
+	if err != nil {
+		return nil, err
+	}
+	iterable, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tok.RIGHT_PAREN, "Expect ')' after loop iterable.")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.loopBody(label)
+	if err != nil {
+		return nil, err
+	}
+	p.node(FOR, mark)
+	return NewFor(keyword, names, iterable, body), nil
+}
+
+// forInAhead tells a for-in loop from a C-style one by the loop variables
+// and 'in' that open it
+func (p *Parser) forInAhead() bool {
+	if !p.check(tok.IDENTIFIER) {
+		return false
+	}
+	switch p.lookAhead(1).TokenType {
+	case tok.IN:
+		return true
+	case tok.COMMA:
+		return p.lookAhead(2).TokenType == tok.IDENTIFIER && p.lookAhead(3).TokenType == tok.IN
+	}
+	return false
+}
+
+// forStep parses the clauses of a C-style for after its '(', the increment
+// stays on the loop so that it runs after a 'continue' too
+func (p *Parser) forStep(mark int, keyword tok.Token, label string) (Stmt, error) {
+	var initializer Stmt
+	var err error
+	initializerMark := p.mark()
+	switch {
+	case p.match(tok.SEMICOLON):
+	case p.match(tok.VAR):
+		initializer, err = p.varDeclaration(initializerMark)
+	default:
+		var expr Expr
+		expr, err = p.Expression()
+		if err == nil {
+			_, err = p.consume(tok.SEMICOLON, "Expect ';' after loop initializer.")
+		}
+		if err == nil {
+			p.node(EXPRESSION, initializerMark)
+			initializer = NewExpression(expr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var condition Expr
+	if !p.check(tok.SEMICOLON) {
+		condition, err = p.Expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(tok.SEMICOLON, "Expect ';' after loop condition.")
+	if err != nil {
+		return nil, err
+	}
+
+	var increment Expr
+	if !p.check(tok.RIGHT_PAREN) {
+		increment, err = p.Expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(tok.RIGHT_PAREN, "Expect ')' after for clauses.")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.loopBody(label)
+	if err != nil {
+		return nil, err
+	}
+	p.node(LOOP, mark)
+	return NewLoop(keyword, initializer, condition, increment, body), nil
+}
+
+// whileStmt      → "while" "(" expression ")" body
+func (p *Parser) whileStatement(mark int, label string) (Stmt, error) {
+	keyword := p.previous()
+	_, err := p.consume(tok.LEFT_PAREN, "Expect '(' after 'while'.")
+	if err != nil {
+		return nil, err
+	}
+	condition, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tok.RIGHT_PAREN, "Expect ')' after condition.")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.loopBody(label)
+	if err != nil {
+		return nil, err
+	}
+	p.node(WHILE, mark)
+	return NewWhile(keyword, condition, body), nil
+}
+
+// body           → block | statement
+func (p *Parser) loopBody(label string) (Stmt, error) {
+	p.loops = append(p.loops, label)
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+
+	if p.check(tok.LEFT_BRACE) {
+		return p.block()
+	}
+	return p.statement()
+}
+
+// mapAhead tells whether the '{' starting a statement opens a map rather
+// than a block: "{}", or a key followed by ':' that is not a loop label
+func (p *Parser) mapAhead() bool {
+	switch p.lookAhead(1).TokenType {
+	case tok.RIGHT_BRACE:
+		return true
+	case tok.IDENTIFIER, tok.STRING, tok.NUMBER, tok.TRUE, tok.FALSE, tok.NIL:
+		if p.lookAhead(2).TokenType != tok.COLON {
+			return false
+		}
+		next := p.lookAhead(3).TokenType
+		return next != tok.FOR && next != tok.WHILE
+	}
+	return false
+}
+
+// breakStmt      → "break" IDENTIFIER? ";"
+// continueStmt   → "continue" IDENTIFIER? ";"
+func (p *Parser) jump(mark int) (Stmt, error) {
+	keyword := p.previous()
+	if len(p.loops) == 0 {
+		return nil, ParseError{
+			token: keyword,
+			msg:   "Can't use '" + keyword.Lexeme + "' outside of a loop.",
+		}
+	}
+
+	var label tok.Token
+	if p.match(tok.IDENTIFIER) {
+		label = p.previous()
+		if !p.inLoop(label.Lexeme) {
+			return nil, ParseError{
+				token: label,
+				msg:   "Unknown loop label.",
+			}
+		}
+	}
+
+	_, err := p.consume(tok.SEMICOLON, "Expect ';' after '"+keyword.Lexeme+"'.")
+	if err != nil {
+		return nil, err
+	}
+	if keyword.TokenType == tok.BREAK {
+		p.node(BREAK, mark)
+		return NewBreak(keyword, label), nil
+	}
+	p.node(CONTINUE, mark)
+	return NewContinue(keyword, label), nil
+}
+
+// inLoop tells whether one of the enclosing loops has the label
+func (p *Parser) inLoop(label string) bool {
+	for _, l := range p.loops {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// returnStmt     → "return" expression? ";"
+func (p *Parser) returnStatement(mark int) (Stmt, error) {
+	keyword := p.previous()
+	if p.functions == 0 {
+		return nil, ParseError{
+			token: keyword,
+			msg:   "Can't return from top-level code.",
+		}
+	}
+
+	var value Expr
+	if !p.check(tok.SEMICOLON) {
+		var err error
+		value, err = p.Expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err := p.consume(tok.SEMICOLON, "Expect ';' after return value.")
+	if err != nil {
+		return nil, err
+	}
+	p.node(RETURN, mark)
+	return NewReturn(keyword, value), nil
+}
+
+// throwStmt      → "throw" expression ";"
+func (p *Parser) throw(mark int) (Stmt, error) {
+	keyword := p.previous()
+	value, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tok.SEMICOLON, "Expect ';' after thrown value.")
+	if err != nil {
+		return nil, err
+	}
+	p.node(THROW, mark)
+	return NewThrow(keyword, value), nil
+}
+
+// tryStmt        → "try" block ( "catch" "(" IDENTIFIER ")" block )? ( "finally" block )?
+func (p *Parser) try(mark int) (Stmt, error) {
+	keyword := p.previous()
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	var name tok.Token
+	var handler Stmt
+	if p.match(tok.CATCH) {
+		_, err = p.consume(tok.LEFT_PAREN, "Expect '(' after 'catch'.")
+		if err != nil {
+			return nil, err
+		}
+		name, err = p.consume(tok.IDENTIFIER, "Expect error name.")
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.consume(tok.RIGHT_PAREN, "Expect ')' after error name.")
+		if err != nil {
+			return nil, err
+		}
+		handler, err = p.block()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var finally Stmt
+	if p.match(tok.FINALLY) {
+		finally, err = p.block()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if handler == nil && finally == nil {
+		return nil, ParseError{
+			token: p.peek(),
+			msg:   "Expect 'catch' or 'finally' after try block.",
+		}
+	}
+	p.node(TRY, mark)
+	return NewTry(keyword, body, name, handler, finally), nil
+}
+
+// block          → "{" declaration* "}"
+func (p *Parser) block() (Stmt, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	mark := p.mark()
+	_, err := p.consume(tok.LEFT_BRACE, "Expect '{' before block.")
+	if err != nil {
+		return nil, err
+	}
+
+	stmts := []Stmt{}
+	for !p.check(tok.RIGHT_BRACE) && !p.isAtEnd() {
+		stmt, err := p.Declaration()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	_, err = p.consume(tok.RIGHT_BRACE, "Expect '}' after block.")
+	if err != nil {
+		return nil, err
+	}
+	p.node(BLOCK, mark)
+	return NewBlock(stmts), nil
+}
+
+// enumDecl       → "enum" IDENTIFIER "{" variant ( "," variant )* "}"
+// variant        → IDENTIFIER ( "(" ( IDENTIFIER ( "," IDENTIFIER )* )? ")" )?
+func (p *Parser) enum(mark int) (Stmt, error) {
+	name, err := p.consume(tok.IDENTIFIER, "Expect enum name.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tok.LEFT_BRACE, "Expect '{' before enum variants.")
+	if err != nil {
+		return nil, err
+	}
+
+	var variants []tok.Token
+	var fields [][]tok.Token
+	seen := map[string]bool{}
+	for {
+		variant, payload, err := p.variant()
+		if err != nil {
+			return nil, err
+		}
+		if seen[variant.Lexeme] {
+			return nil, ParseError{
+				token: variant,
+				msg:   "Duplicate variant name.",
+			}
+		}
+		seen[variant.Lexeme] = true
+		variants = append(variants, variant)
+		fields = append(fields, payload)
+		if !p.match(tok.COMMA) {
+			break
+		}
+	}
+
+	_, err = p.consume(tok.RIGHT_BRACE, "Expect '}' after enum variants.")
+	if err != nil {
+		return nil, err
+	}
+	p.node(ENUM, mark)
+	return NewEnum(name, variants, fields), nil
+}
+
+// variant returns the name of an enum variant and its payload fields, nil
+// when it has no parentheses
+func (p *Parser) variant() (tok.Token, []tok.Token, error) {
+	mark := p.mark()
+	name, err := p.consume(tok.IDENTIFIER, "Expect variant name.")
+	if err != nil {
+		return name, nil, err
+	}
+	if !p.match(tok.LEFT_PAREN) {
+		p.node(VARIANT, mark)
+		return name, nil, nil
+	}
+
+	fields := []tok.Token{}
+	if !p.check(tok.RIGHT_PAREN) {
+		for {
+			field, err := p.consume(tok.IDENTIFIER, "Expect field name.")
+			if err != nil {
+				return name, fields, err
+			}
+			fields = append(fields, field)
+			if !p.match(tok.COMMA) {
+				break
+			}
+		}
+	}
+	_, err = p.consume(tok.RIGHT_PAREN, "Expect ')' after variant fields.")
+	if err != nil {
+		return name, fields, err
+	}
+	p.node(VARIANT, mark)
+	return name, fields, nil
+}
+
+// classDecl      → "class" IDENTIFIER ( "<" IDENTIFIER )? ( "with" names )? "{" member* "}"
+func (p *Parser) class(mark int) (Stmt, error) {
+	name, err := p.consume(tok.IDENTIFIER, "Expect class name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var superclass tok.Token
+	if p.match(tok.LESS) {
+		superclass, err = p.consume(tok.IDENTIFIER, "Expect superclass name.")
+		if err != nil {
+			return nil, err
+		}
+		if superclass.Lexeme == name.Lexeme {
+			return nil, ParseError{
+				token: superclass,
+				msg:   "A class can't inherit from itself.",
+			}
+		}
+	}
+
+	var traits []tok.Token
+	if p.match(tok.WITH) {
+		seen := map[string]bool{}
+		for {
+			trait, err := p.consume(tok.IDENTIFIER, "Expect trait name.")
+			if err != nil {
+				return nil, err
+			}
+			if seen[trait.Lexeme] {
+				return nil, ParseError{
+					token: trait,
+					msg:   "Duplicate trait name.",
+				}
+			}
+			seen[trait.Lexeme] = true
+			traits = append(traits, trait)
+			if !p.match(tok.COMMA) {
+				break
+			}
+		}
+	}
+
+	methods, fields, err := p.members("class", false)
+	if err != nil {
+		return nil, err
+	}
+	p.node(CLASS, mark)
+	return NewClass(name, superclass, traits, methods, fields), nil
+}
+
+// traitDecl      → "trait" IDENTIFIER "{" member* "}"
+func (p *Parser) trait(mark int) (Stmt, error) {
+	name, err := p.consume(tok.IDENTIFIER, "Expect trait name.")
+	if err != nil {
+		return nil, err
+	}
+	methods, _, err := p.members("trait", true)
+	if err != nil {
+		return nil, err
+	}
+	p.node(TRAIT, mark)
+	return NewTrait(name, methods), nil
+}
+
+// member         → "static"? ( field | getter | setter | method )
+// field          → IDENTIFIER ( ":" annotation )? ( "=" expression )? ";"
+// getter         → IDENTIFIER block
+// setter         → "set" IDENTIFIER "(" parameter ")" block
+//
+// members parses the body of a class or trait, only traits may leave a
+// method to be provided by the classes using them and only classes have
+// fields and static members
+func (p *Parser) members(kind string, required bool) ([]Function, []Field, error) {
+	_, err := p.consume(tok.LEFT_BRACE, "Expect '{' before "+kind+" body.")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	methods := []Function{}
+	var fields []Field
+	// A getter and a setter may share their name, nothing else may
+	seen := map[string][]FunctionKind{}
+	for !p.check(tok.RIGHT_BRACE) && !p.isAtEnd() {
+		mark := p.mark()
+		static := p.match(tok.STATIC)
+		if static && required {
+			return nil, nil, ParseError{
+				token: p.previous(),
+				msg:   "Traits can't have static members.",
+			}
+		}
+
+		var name tok.Token
+		memberKind := FUNCTION_KIND
+		if p.check(tok.IDENTIFIER) && p.fieldAhead() {
+			if required {
+				return nil, nil, ParseError{
+					token: p.peek(),
+					msg:   "Traits can't have fields.",
+				}
+			}
+			field, err := p.field(mark, static)
+			if err != nil {
+				return nil, nil, err
+			}
+			name = field.name
+			fields = append(fields, field)
+		} else {
+			method, err := p.method(mark, static, required)
+			if err != nil {
+				return nil, nil, err
+			}
+			name, memberKind = method.name, method.kind
+			methods = append(methods, method)
+		}
+
+		key := name.Lexeme
+		if static {
+			key = "static " + key
+		}
+		for _, other := range seen[key] {
+			if other == memberKind || other == FUNCTION_KIND || memberKind == FUNCTION_KIND {
+				return nil, nil, ParseError{
+					token: name,
+					msg:   "Duplicate member name.",
+				}
+			}
+		}
+		seen[key] = append(seen[key], memberKind)
+	}
+
+	_, err = p.consume(tok.RIGHT_BRACE, "Expect '}' after "+kind+" body.")
+	if err != nil {
+		return nil, nil, err
+	}
+	return methods, fields, nil
+}
+
+// fieldAhead tells a field from a method by what follows its name
+func (p *Parser) fieldAhead() bool {
+	switch p.lookAhead(1).TokenType {
+	case tok.COLON, tok.EQUAL, tok.SEMICOLON:
+		return true
+	}
+	return false
+}
+
+func (p *Parser) field(mark int, static bool) (Field, error) {
+	name := p.advance()
+	var annotation *Annotation
+	var err error
+	if p.match(tok.COLON) {
+		annotation, err = p.typed("field")
+		if err != nil {
+			return Field{}, err
+		}
+	}
+
+	var value Expr
+	if p.match(tok.EQUAL) {
+		value, err = p.Expression()
+		if err != nil {
+			return Field{}, err
+		}
+	}
+	_, err = p.consume(tok.SEMICOLON, "Expect ';' after field declaration.")
+	if err != nil {
+		return Field{}, err
+	}
+	p.node(FIELD, mark)
+	return NewField(name, annotation, value, static), nil
+}
+
+// method parses a method, getter or setter of a class or trait body
+func (p *Parser) method(mark int, static, required bool) (Function, error) {
+	async := p.match(tok.ASYNC)
+
+	// 'set' is only a keyword before the name of a setter
+	if p.check(tok.IDENTIFIER) && p.peek().Lexeme == "set" && p.lookAhead(1).TokenType == tok.IDENTIFIER {
+		p.advance()
+		setter, err := p.function(mark, async, required)
+		if err != nil {
+			return Function{}, err
+		}
+		if len(setter.params) != 1 || setter.params[0].rest {
+			return Function{}, ParseError{
+				token: setter.name,
+				msg:   "A setter takes exactly one parameter.",
+			}
+		}
+		setter.static, setter.kind = static, SETTER_KIND
+		return setter, nil
+	}
+
+	if p.check(tok.IDENTIFIER) && p.lookAhead(1).TokenType == tok.LEFT_BRACE {
+		name := p.advance()
+		body, err := p.functionBody()
+		if err != nil {
+			return Function{}, err
+		}
+		p.node(FUNCTION, mark)
+		return NewFunction(name, []Parameter{}, nil, body, async, static, GETTER_KIND), nil
+	}
+
+	method, err := p.function(mark, async, required)
+	if err != nil {
+		return Function{}, err
+	}
+	method.static = static
+	return method, nil
+}
+
+// method         → "async"? IDENTIFIER "(" parameters? ")" ( ":" annotation )? ( block | ";" )
+// funDecl        → "async"? "fun" IDENTIFIER "(" parameters? ")" ( ":" annotation )? block
+// parameters     → parameter ( "," parameter )*
+func (p *Parser) function(mark int, async, required bool) (Function, error) {
+	kind := "method"
+	if p.previous().TokenType == tok.FUN {
+		kind = "function"
+	}
+	name, err := p.consume(tok.IDENTIFIER, "Expect "+kind+" name.")
+	if err != nil {
+		return Function{}, err
+	}
+	_, err = p.consume(tok.LEFT_PAREN, "Expect '(' after "+kind+" name.")
+	if err != nil {
+		return Function{}, err
+	}
+
+	params := []Parameter{}
+	seen := map[string]bool{}
+	if !p.check(tok.RIGHT_PAREN) {
+		for {
+			param, err := p.parameter()
+			if err != nil {
+				return Function{}, err
+			}
+			if seen[param.name.Lexeme] {
+				return Function{}, ParseError{
+					token: param.name,
+					msg:   "Duplicate parameter name.",
+				}
+			}
+			// Arguments fill the parameters in order, those with a
+			// default may only be followed by others with one
+			if len(params) > 0 && params[len(params)-1].value != nil && param.value == nil && !param.rest {
+				return Function{}, ParseError{
+					token: param.name,
+					msg:   "Expect default value after a parameter with one.",
+				}
+			}
+			seen[param.name.Lexeme] = true
+			params = append(params, param)
+			if !p.match(tok.COMMA) {
+				break
+			}
+			if param.rest {
+				return Function{}, ParseError{
+					token: p.previous(),
+					msg:   "Rest parameter must be the last one.",
+				}
+			}
+		}
+	}
+	_, err = p.consume(tok.RIGHT_PAREN, "Expect ')' after parameters.")
+	if err != nil {
+		return Function{}, err
+	}
+
+	var returns *Annotation
+	if p.match(tok.COLON) {
+		returns, err = p.typed("return")
+		if err != nil {
+			return Function{}, err
+		}
+	}
+
+	var body Stmt
+	switch {
+	case required && p.match(tok.SEMICOLON):
+	case p.check(tok.LEFT_BRACE):
+		body, err = p.functionBody()
+		if err != nil {
+			return Function{}, err
+		}
+	case required:
+		return Function{}, ParseError{
+			token: p.peek(),
+			msg:   "Expect '{' or ';' after parameters.",
+		}
+	default:
+		return Function{}, ParseError{
+			token: p.peek(),
+			msg:   "Expect '{' before " + kind + " body.",
+		}
+	}
+	p.node(FUNCTION, mark)
+	return NewFunction(name, params, returns, body, async, false, FUNCTION_KIND), nil
+}
+
+// parameter      → "..."? IDENTIFIER ( ":" annotation )? ( "=" expression )?
+func (p *Parser) parameter() (Parameter, error) {
+	mark := p.mark()
+	rest := p.match(tok.ELLIPSIS)
+	name, err := p.consume(tok.IDENTIFIER, "Expect parameter name.")
+	if err != nil {
+		return Parameter{}, err
+	}
+
+	var annotation *Annotation
+	if p.match(tok.COLON) {
+		annotation, err = p.typed("parameter")
+		if err != nil {
+			return Parameter{}, err
+		}
+	}
+
+	var value Expr
+	if p.match(tok.EQUAL) {
+		if rest {
+			return Parameter{}, ParseError{
+				token: p.previous(),
+				msg:   "Rest parameter can't have a default value.",
+			}
+		}
+		value, err = p.Expression()
+		if err != nil {
+			return Parameter{}, err
+		}
+	}
+	p.node(PARAMETER, mark)
+	return NewParameter(name, annotation, value, rest), nil
+}
+
+// functionBody parses a block where return is allowed and the enclosing
+// loops are out of reach
+func (p *Parser) functionBody() (Stmt, error) {
+	loops := p.loops
+	p.loops = nil
+	p.functions++
+	defer func() {
+		p.loops = loops
+		p.functions--
+	}()
+	return p.block()
+}
+
+// varDecl        → "var" ( IDENTIFIER ( ":" annotation )? | sequence | mapping ) "=" expression ";"
+func (p *Parser) varDeclaration(mark int) (Stmt, error) {
+	if p.check(tok.LEFT_BRACKET) || p.check(tok.LEFT_BRACE) {
+		return p.varPattern(mark)
+	}
+
+	name, err := p.consume(tok.IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var annotation *Annotation
+	if p.match(tok.COLON) {
+		annotation, err = p.typed("variable")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.consume(tok.EQUAL, "Expect '=' before variable value.")
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tok.SEMICOLON, "Expect ';' after variable declaration.")
+	if err != nil {
+		return nil, err
+	}
+	p.node(VAR, mark)
+	return NewVar(name, annotation, initializer), nil
+}
+
+// constDecl      → "const" IDENTIFIER "=" expression ";"
+func (p *Parser) constDeclaration(mark int) (Stmt, error) {
+	name, err := p.consume(tok.IDENTIFIER, "Expect constant name.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tok.EQUAL, "Expect '=' after constant name.")
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tok.SEMICOLON, "Expect ';' after constant declaration.")
+	if err != nil {
+		return nil, err
+	}
+	p.node(CONST, mark)
+	return NewConst(name, initializer), nil
+}
+
+// annotation     → IDENTIFIER ( "<" annotation ( "," annotation )* ">" )?
+func (p *Parser) annotation() (Annotation, error) {
+	if err := p.enter(); err != nil {
+		return Annotation{}, err
+	}
+	defer p.leave()
+
+	mark := p.mark()
+	name, err := p.consume(tok.IDENTIFIER, "Expect type name.")
+	if err != nil {
+		return Annotation{}, err
+	}
+
+	var arguments []Annotation
+	if p.match(tok.LESS) {
+		for {
+			argument, err := p.annotation()
+			if err != nil {
+				return Annotation{}, err
+			}
+			arguments = append(arguments, argument)
+			// A '>>' closing the argument closes this list too
+			if p.split > 0 || !p.match(tok.COMMA) {
+				break
+			}
+		}
+
+		switch {
+		case p.split > 0:
+			p.split--
+		case p.match(tok.GREATER):
+		case p.match(tok.GREATER_GREATER):
+			p.split++
+		default:
+			return Annotation{}, ParseError{
+				token: p.peek(),
+				msg:   "Expect '>' after type arguments.",
+			}
+		}
+	}
+	p.node(ANNOTATION, mark)
+	return NewAnnotation(name, arguments), nil
+}
+
+// varPattern declares the variables bound by a list or map pattern
+func (p *Parser) varPattern(mark int) (Stmt, error) {
+	keyword := p.previous()
+	pattern, err := p.pattern()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, name := range bound(pattern) {
+		if seen[name.Lexeme] {
+			return nil, ParseError{
+				token: name,
+				msg:   "Duplicate variable name in pattern.",
+			}
+		}
+		seen[name.Lexeme] = true
+	}
+
+	_, err = p.consume(tok.EQUAL, "Expect '=' after variable pattern.")
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tok.SEMICOLON, "Expect ';' after variable declaration.")
+	if err != nil {
+		return nil, err
+	}
+	p.node(VAR, mark)
+	return NewVarPattern(keyword, pattern, initializer), nil
+}
+
+// bound returns the names a pattern binds, every alternative binds those
+// of the first one
+func bound(pattern Pattern) []tok.Token {
+	switch pt := pattern.(type) {
+	case Alternatives:
+		return bound(pt.patterns[0])
+	case Binding:
+		return []tok.Token{pt.name}
+	case Rest:
+		return []tok.Token{pt.name}
+	case Instance:
+		return boundAll(pt.fields)
+	case Mapping:
+		return boundAll(pt.values)
+	case Sequence:
+		return boundAll(pt.elements)
+	}
+	return nil
+}
+
+func boundAll(patterns []Pattern) []tok.Token {
+	var names []tok.Token
+	for _, pattern := range patterns {
+		names = append(names, bound(pattern)...)
+	}
+	return names
+}
+
+// typed parses the annotation after the ':' giving the type of a variable,
+// parameter or return value, a '>' left from a '>>' has nothing to close
+func (p *Parser) typed(what string) (*Annotation, error) {
+	annotation, err := p.annotation()
+	if err != nil {
+		return nil, err
+	}
+	if p.split > 0 {
+		p.split = 0
+		return nil, ParseError{
+			token: p.previous(),
+			msg:   "Unexpected '>' after " + what + " type.",
+		}
+	}
+	return &annotation, nil
+}
+
+// importDecl     → "import" ( STRING ( "as" IDENTIFIER )? | "{" names "}" "from" STRING ) ";"
+// names          → IDENTIFIER ( "," IDENTIFIER )*
+func (p *Parser) importDecl(mark int) (Stmt, error) {
+	keyword := p.previous()
+	// Modules are loaded once, before the code importing them runs
+	if p.depth > 0 {
+		return nil, ParseError{
+			token: keyword,
+			msg:   "Can only import at the top level.",
+		}
+	}
+
+	var path, alias tok.Token
+	var names []tok.Token
+	var err error
+
+	if p.match(tok.LEFT_BRACE) {
+		names = []tok.Token{}
+		seen := map[string]bool{}
+		for {
+			name, err := p.consume(tok.IDENTIFIER, "Expect imported name.")
+			if err != nil {
+				return nil, err
+			}
+			if seen[name.Lexeme] {
+				return nil, ParseError{
+					token: name,
+					msg:   "Duplicate imported name.",
+				}
+			}
+			seen[name.Lexeme] = true
+			names = append(names, name)
+			if !p.match(tok.COMMA) {
+				break
+			}
+		}
+		_, err = p.consume(tok.RIGHT_BRACE, "Expect '}' after imported names.")
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.consume(tok.FROM, "Expect 'from' after imported names.")
+		if err != nil {
+			return nil, err
+		}
+		path, err = p.consume(tok.STRING, "Expect module path.")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		path, err = p.consume(tok.STRING, "Expect module path.")
+		if err != nil {
+			return nil, err
+		}
+		if p.match(tok.AS) {
+			alias, err = p.consume(tok.IDENTIFIER, "Expect module name after 'as'.")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_, err = p.consume(tok.SEMICOLON, "Expect ';' after import.")
+	if err != nil {
+		return nil, err
+	}
+	p.node(IMPORT, mark)
+	return NewImport(keyword, path, alias, names), nil
+}
+
+// expression     → assignment
+func (p *Parser) Expression() (Expr, error) {
+	return p.Assignment()
+}
+
+// assignment     → "yield" assignment? | target ( "=" | "+=" | "-=" | "*=" | "/=" | "%=" ) assignment | equality
+// target         → IDENTIFIER | postfix "[" expression "]" | postfix "." IDENTIFIER | list | map
+func (p *Parser) Assignment() (Expr, error) {
+	mark := p.mark()
+	if p.match(tok.YIELD) {
+		return p.yield(mark)
+	}
+
+	expr, err := p.Equality()
+	if err != nil {
+		return expr, err
+	}
+
+	if p.match(tok.EQUAL, tok.PLUS_EQUAL, tok.MINUS_EQUAL, tok.STAR_EQUAL, tok.SLASH_EQUAL, tok.PERCENT_EQUAL) {
+		operator := p.previous()
+		value, err := p.Assignment()
+		if err != nil {
+			return value, err
+		}
+
+		if operator.TokenType == tok.EQUAL {
+			switch e := expr.(type) {
+			case Index:
+				p.node(INDEX_SET, mark)
+				return NewIndexSet(e.object, e.bracket, e.index, value), nil
+			case Variable:
+				p.node(ASSIGN, mark)
+				return NewAssign(e.name, value), nil
+			case Get:
+				p.node(SET, mark)
+				return NewSet(e.object, e.name, value), nil
+			}
+			if pattern, ok := destructure(expr); ok {
+				p.node(DESTRUCTURE, mark)
+				return NewDestructure(pattern, operator, value), nil
+			}
+		} else if isTarget(expr) {
+			p.node(COMPOUND, mark)
+			return NewCompound(expr, operator, value), nil
+		}
+
+		return expr, ParseError{
+			token: operator,
+			msg:   "Invalid assignment target.",
+		}
+	}
+
+	return expr, nil
+}
+
+// yield parses the value of a yield, it has none when the yield closes
+// whatever encloses it
+func (p *Parser) yield(mark int) (Expr, error) {
+	// Yields nest without going through unary
+	if err := p.enter(); err != nil {
+		return Literal{}, err
+	}
+	defer p.leave()
+
+	keyword := p.previous()
+	if p.functions == 0 {
+		return nil, ParseError{
+			token: keyword,
+			msg:   "Can't yield from top-level code.",
+		}
+	}
+
+	var value Expr
+	if !p.check(tok.RIGHT_PAREN) && !p.check(tok.RIGHT_BRACKET) && !p.check(tok.RIGHT_BRACE) &&
+		!p.check(tok.COMMA) && !p.check(tok.COLON) && !p.check(tok.SEMICOLON) && !p.isAtEnd() {
+		var err error
+		value, err = p.Assignment()
+		if err != nil {
+			return value, err
+		}
+	}
+	p.node(YIELD, mark)
+	return NewYield(keyword, value), nil
+}
+
+// isTarget tells whether expr can be assigned to
+func isTarget(expr Expr) bool {
+	switch expr.(type) {
+	case Get, Index, Variable:
+		return true
+	}
+	return false
+}
+
+// destructure turns a list or map literal on the left of '=' into the
+// pattern it spells, when it only holds variables
+func destructure(expr Expr) (Pattern, bool) {
+	switch e := expr.(type) {
+	case Variable:
+		if e.name.Lexeme == "_" {
+			return NewWildcard(e.name), true
+		}
+		return NewBinding(e.name), true
+	case ListLiteral:
+		elements := []Pattern{}
+		rest := false
+		for _, element := range e.elements {
+			if s, ok := element.(Spread); ok {
+				v, ok := s.expression.(Variable)
+				if !ok || rest {
+					return nil, false
+				}
+				rest = true
+				elements = append(elements, NewRest(v.name))
+				continue
+			}
+			pattern, ok := destructure(element)
+			if !ok {
+				return nil, false
+			}
+			elements = append(elements, pattern)
+		}
+		return NewSequence(elements), true
+	case MapLiteral:
+		values := []Pattern{}
+		for i, key := range e.keys {
+			if _, ok := key.(Literal); !ok {
+				return nil, false
+			}
+			value, ok := destructure(e.values[i])
+			if !ok {
+				return nil, false
+			}
+			values = append(values, value)
+		}
+		return NewMapping(e.keys, values), true
+	}
+	return nil, false
+}
+
+// equality       → comparison ( ( "!=" | "==" ) comparison )*
+func (p *Parser) Equality() (Expr, error) {
+	mark := p.mark()
+	expr, err := p.Comparison()
+	if err != nil {
+		return expr, err
+	}
+
+	for p.match(tok.BANG_EQUAL, tok.EQUAL_EQUAL) {
+		operator := p.previous()
+		right, err := p.Comparison()
+		if err != nil {
+			return right, err
+		}
+		expr = NewBinary(expr, operator, right)
+		p.node(BINARY, mark)
+	}
+
+	return expr, nil
+}
+
+// comparison     → bitOr ( ( ">" | ">=" | "<" | "<=" ) bitOr )*
+func (p *Parser) Comparison() (Expr, error) {
+	mark := p.mark()
+	expr, err := p.bitOr()
+	if err != nil {
+		return expr, err
+	}
+
+	for p.match(tok.GREATER, tok.GREATER_EQUAL, tok.LESS, tok.LESS_EQUAL) {
+		operator := p.previous()
+		right, err := p.bitOr()
+		if err != nil {
+			return right, err
+		}
+		expr = NewBinary(expr, operator, right)
+		p.node(BINARY, mark)
+	}
+
+	return expr, nil
 }
 
-func NewParser(tokens []tok.Token) Parser {
-	return Parser{tokens, 0}
-}
+// bitOr          → bitXor ( "|" bitXor )*
+func (p *Parser) bitOr() (Expr, error) {
+	mark := p.mark()
+	expr, err := p.bitXor()
+	if err != nil {
+		return expr, err
+	}
 
-func (p *Parser) Parse() (Expr, error) {
-	return p.Expression()
-}
+	for p.match(tok.PIPE) {
+		operator := p.previous()
+		right, err := p.bitXor()
+		if err != nil {
+			return right, err
+		}
+		expr = NewBinary(expr, operator, right)
+		p.node(BINARY, mark)
+	}
 
-func (e ParseError) Error() string {
-	scanner.Error(e.token, e.msg)
-	return ""
+	return expr, nil
 }
 
-// expression     → equality
-func (p *Parser) Expression() (Expr, error) {
-	return p.Equality()
+// bitXor         → bitAnd ( "^" bitAnd )*
+func (p *Parser) bitXor() (Expr, error) {
+	mark := p.mark()
+	expr, err := p.bitAnd()
+	if err != nil {
+		return expr, err
+	}
+
+	for p.match(tok.CARET) {
+		operator := p.previous()
+		right, err := p.bitAnd()
+		if err != nil {
+			return right, err
+		}
+		expr = NewBinary(expr, operator, right)
+		p.node(BINARY, mark)
+	}
+
+	return expr, nil
 }
 
-// equality       → comparison ( ( "!=" | "==" ) comparison )*
-func (p *Parser) Equality() (Expr, error) {
-	expr, err := p.Comparison()
+// bitAnd         → shift ( "&" shift )*
+func (p *Parser) bitAnd() (Expr, error) {
+	mark := p.mark()
+	expr, err := p.shift()
 	if err != nil {
 		return expr, err
 	}
 
-	for p.match(tok.BANG_EQUAL, tok.EQUAL_EQUAL) {
+	for p.match(tok.AMPERSAND) {
 		operator := p.previous()
-		right, err := p.Comparison()
+		right, err := p.shift()
 		if err != nil {
 			return right, err
 		}
 		expr = NewBinary(expr, operator, right)
-
+		p.node(BINARY, mark)
 	}
 
 	return expr, nil
 }
 
-// comparison     → term ( ( ">" | ">=" | "<" | "<=" ) term )*
-func (p *Parser) Comparison() (Expr, error) {
+// shift          → term ( ( "<<" | ">>" ) term )*
+func (p *Parser) shift() (Expr, error) {
+	mark := p.mark()
 	expr, err := p.term()
 	if err != nil {
 		return expr, err
 	}
 
-	for p.match(tok.GREATER, tok.GREATER_EQUAL, tok.LESS, tok.LESS_EQUAL) {
+	for p.match(tok.LESS_LESS, tok.GREATER_GREATER) {
 		operator := p.previous()
 		right, err := p.term()
 		if err != nil {
 			return right, err
 		}
 		expr = NewBinary(expr, operator, right)
+		p.node(BINARY, mark)
 	}
 
 	return expr, nil
 }
 
-// unary          → ( "!" | "-" ) unary | primary
+// unary          → ( "!" | "-" | "~" ) unary | "await" unary | "spawn" postfix | power
 func (p *Parser) Unary() (Expr, error) {
+	// Every nested expression goes through here
+	if err := p.enter(); err != nil {
+		return Literal{}, err
+	}
+	defer p.leave()
 
-	if p.match(tok.BANG, tok.MINUS) {
+	mark := p.mark()
+	if p.match(tok.BANG, tok.MINUS, tok.TILDE) {
 		operator := p.previous()
 		right, err := p.Unary()
 		if err != nil {
 			return right, err
 		}
+		p.node(UNARY, mark)
 		return NewUnary(operator, right), nil
 	}
 
-	return p.Primary()
+	if p.match(tok.AWAIT) {
+		keyword := p.previous()
+		value, err := p.Unary()
+		if err != nil {
+			return value, err
+		}
+		p.node(AWAIT, mark)
+		return NewAwait(keyword, value), nil
+	}
+
+	if p.match(tok.SPAWN) {
+		keyword := p.previous()
+		call, err := p.Postfix()
+		if err != nil {
+			return call, err
+		}
+		if _, ok := call.(Call); !ok {
+			return call, ParseError{
+				token: keyword,
+				msg:   "Expect call after 'spawn'.",
+			}
+		}
+		p.node(SPAWN, mark)
+		return NewSpawn(keyword, call), nil
+	}
+
+	return p.power()
+}
+
+// power          → update ( "**" unary )?
+func (p *Parser) power() (Expr, error) {
+	mark := p.mark()
+	expr, err := p.update()
+	if err != nil {
+		return expr, err
+	}
+
+	// Right associative, and binding tighter than a unary on its left
+	if p.match(tok.STAR_STAR) {
+		operator := p.previous()
+		right, err := p.Unary()
+		if err != nil {
+			return right, err
+		}
+		expr = NewBinary(expr, operator, right)
+		p.node(BINARY, mark)
+	}
+
+	return expr, nil
+}
+
+// update         → ( "++" | "--" ) postfix | postfix ( "++" | "--" )?
+func (p *Parser) update() (Expr, error) {
+	mark := p.mark()
+	if p.match(tok.PLUS_PLUS, tok.MINUS_MINUS) {
+		operator := p.previous()
+		target, err := p.Postfix()
+		if err != nil {
+			return target, err
+		}
+		if !isTarget(target) {
+			return target, ParseError{
+				token: operator,
+				msg:   "Invalid assignment target.",
+			}
+		}
+		p.node(UPDATE, mark)
+		return NewUpdate(operator, target, true), nil
+	}
+
+	expr, err := p.Postfix()
+	if err != nil {
+		return expr, err
+	}
+
+	if p.match(tok.PLUS_PLUS, tok.MINUS_MINUS) {
+		operator := p.previous()
+		if !isTarget(expr) {
+			return expr, ParseError{
+				token: operator,
+				msg:   "Invalid assignment target.",
+			}
+		}
+		p.node(UPDATE, mark)
+		return NewUpdate(operator, expr, false), nil
+	}
+
+	return expr, nil
+}
+
+// postfix        → primary ( "[" subscript "]" | "(" arguments? ")" | "." IDENTIFIER )*
+func (p *Parser) Postfix() (Expr, error) {
+	mark := p.mark()
+	expr, err := p.Primary()
+	if err != nil {
+		return expr, err
+	}
+
+	for {
+		if p.match(tok.LEFT_BRACKET) {
+			expr, err = p.subscript(expr, mark)
+		} else if p.match(tok.LEFT_PAREN) {
+			expr, err = p.call(expr, mark)
+		} else if p.match(tok.DOT) {
+			var name tok.Token
+			name, err = p.consume(tok.IDENTIFIER, "Expect property name after '.'.")
+			if err == nil {
+				expr = NewGet(expr, name)
+				p.node(GET, mark)
+			}
+		} else {
+			break
+		}
+		if err != nil {
+			return expr, err
+		}
+	}
+
+	return expr, nil
+}
+
+// arguments      → argument ( "," argument )*
+func (p *Parser) call(callee Expr, mark int) (Expr, error) {
+	arguments := []Expr{}
+	named := map[string]bool{}
+	if !p.check(tok.RIGHT_PAREN) {
+		for {
+			start := p.peek()
+			argument, err := p.argument()
+			if err != nil {
+				return argument, err
+			}
+
+			// Named arguments come last, each name once
+			if n, ok := argument.(Named); ok {
+				if named[n.name.Lexeme] {
+					return argument, ParseError{
+						token: n.name,
+						msg:   "Duplicate argument name.",
+					}
+				}
+				named[n.name.Lexeme] = true
+			} else if len(named) > 0 {
+				return argument, ParseError{
+					token: start,
+					msg:   "Expect named argument after named arguments.",
+				}
+			}
+
+			arguments = append(arguments, argument)
+			if !p.match(tok.COMMA) {
+				break
+			}
+		}
+	}
+
+	paren, err := p.consume(tok.RIGHT_PAREN, "Expect ')' after arguments.")
+	if err != nil {
+		return callee, err
+	}
+	p.node(CALL, mark)
+	return NewCall(callee, paren, arguments), nil
+}
+
+// argument       → IDENTIFIER ":" expression | element
+func (p *Parser) argument() (Expr, error) {
+	mark := p.mark()
+	expr, err := p.element()
+	if err != nil {
+		return expr, err
+	}
+
+	if v, ok := expr.(Variable); ok && p.match(tok.COLON) {
+		value, err := p.Expression()
+		if err != nil {
+			return value, err
+		}
+		p.node(NAMED, mark)
+		return NewNamed(v.name, value), nil
+	}
+
+	return expr, nil
+}
+
+// subscript      → expression | expression? ":" expression?
+func (p *Parser) subscript(object Expr, mark int) (Expr, error) {
+	bracket := p.previous()
+	var start, end Expr
+	var err error
+
+	if !p.check(tok.COLON) {
+		start, err = p.Expression()
+		if err != nil {
+			return start, err
+		}
+		if !p.match(tok.COLON) {
+			_, err = p.consume(tok.RIGHT_BRACKET, "Expect ']' after index.")
+			if err != nil {
+				return start, err
+			}
+			p.node(INDEX, mark)
+			return NewIndex(object, bracket, start), nil
+		}
+	} else {
+		p.advance()
+	}
+
+	if !p.check(tok.RIGHT_BRACKET) {
+		end, err = p.Expression()
+		if err != nil {
+			return end, err
+		}
+	}
+	_, err = p.consume(tok.RIGHT_BRACKET, "Expect ']' after slice.")
+	if err != nil {
+		return object, err
+	}
+	p.node(SLICE, mark)
+	return NewSlice(object, bracket, start, end), nil
 }
 
-// primary        → NUMBER | STRING | "true" | "false" | "nil" | "(" expression ")"
+// primary        → NUMBER | STRING | "true" | "false" | "nil" | IDENTIFIER | "(" expression ")" | list | map | match
+// list           → "[" ( element ( "," element )* )? "]"
+// element        → "..." expression | expression
+// map            → "{" ( entry ( "," entry )* )? "}"
+// entry          → expression ":" expression
 func (p *Parser) Primary() (Expr, error) {
-	if p.match(tok.FALSE) {
-		return NewLiteral(false), nil
+	mark := p.mark()
+	if p.match(tok.FALSE, tok.TRUE, tok.NIL, tok.NUMBER, tok.STRING) {
+		p.node(LITERAL, mark)
+		return NewLiteral(literalValue(p.previous())), nil
 	}
-	if p.match(tok.TRUE) {
-		return NewLiteral(true), nil
+
+	if p.match(tok.IDENTIFIER) {
+		p.node(VARIABLE, mark)
+		return NewVariable(p.previous()), nil
 	}
-	if p.match(tok.NIL) {
-		return NewLiteral(nil), nil
+
+	if p.match(tok.THIS) {
+		p.node(THIS, mark)
+		return NewThis(p.previous()), nil
 	}
 
-	if p.match(tok.NUMBER, tok.STRING) {
-		return NewLiteral(p.previous().Literal), nil
+	if p.match(tok.MATCH) {
+		return p.matchArms(mark)
 	}
 
 	if p.match(tok.LEFT_PAREN) {
+		// A grouping nested deeper than allowed here is parsed again for the error
+		if g, found := p.groupings[mark]; found && p.depth+g.depth <= maxDepth {
+			return p.skip(g), nil
+		}
+		outer := p.deepest
+		p.deepest = p.depth
 		expr, err := p.Expression()
+		depth := p.deepest - p.depth
+		if p.deepest < outer {
+			p.deepest = outer
+		}
+		if err != nil {
+			return expr, err
+		}
+		_, err = p.consume(tok.RIGHT_PAREN, "Expect ')' after expression.")
 		if err != nil {
 			return expr, err
 		}
-		p.consume(tok.RIGHT_PAREN, "Expect ')' after expression.")
+		p.node(GROUPING, mark)
+		if p.groupings != nil {
+			p.groupings[mark] = grouping{NewGrouping(expr), p.consumed - mark, depth}
+		}
 		return NewGrouping(expr), nil
 	}
 
+	if p.match(tok.LEFT_BRACKET) {
+		elements := []Expr{}
+		if !p.check(tok.RIGHT_BRACKET) {
+			for {
+				element, err := p.element()
+				if err != nil {
+					return element, err
+				}
+				elements = append(elements, element)
+				if !p.match(tok.COMMA) {
+					break
+				}
+			}
+		}
+		_, err := p.consume(tok.RIGHT_BRACKET, "Expect ']' after list elements.")
+		if err != nil {
+			return NewListLiteral(elements), err
+		}
+		p.node(LIST, mark)
+		return NewListLiteral(elements), nil
+	}
+
+	// A brace starting an expression opens a map, statements tell blocks apart
+	if p.match(tok.LEFT_BRACE) {
+		brace := p.previous()
+		keys, values := []Expr{}, []Expr{}
+		if !p.check(tok.RIGHT_BRACE) {
+			for {
+				key, err := p.Expression()
+				if err != nil {
+					return key, err
+				}
+				_, err = p.consume(tok.COLON, "Expect ':' after map key.")
+				if err != nil {
+					return key, err
+				}
+				value, err := p.Expression()
+				if err != nil {
+					return value, err
+				}
+				keys = append(keys, key)
+				values = append(values, value)
+				if !p.match(tok.COMMA) {
+					break
+				}
+			}
+		}
+		_, err := p.consume(tok.RIGHT_BRACE, "Expect '}' after map entries.")
+		if err != nil {
+			return NewMapLiteral(brace, keys, values), err
+		}
+		p.node(MAP, mark)
+		return NewMapLiteral(brace, keys, values), nil
+	}
+
 	return Literal{}, ParseError{
 		token: p.peek(),
 		msg:   "Expect expression.",
 	}
 }
 
+// element parses a list element, spread with "..."
+func (p *Parser) element() (Expr, error) {
+	mark := p.mark()
+	if p.match(tok.ELLIPSIS) {
+		ellipsis := p.previous()
+		expr, err := p.Expression()
+		if err != nil {
+			return expr, err
+		}
+		p.node(SPREAD, mark)
+		return NewSpread(ellipsis, expr), nil
+	}
+	return p.Expression()
+}
+
+// match          → "match" expression "{" arm ( "," arm )* "}"
+// arm            → pattern ( "if" expression )? "=>" expression
+func (p *Parser) matchArms(mark int) (Expr, error) {
+	keyword := p.previous()
+	value, err := p.Expression()
+	if err != nil {
+		return value, err
+	}
+	_, err = p.consume(tok.LEFT_BRACE, "Expect '{' after match value.")
+	if err != nil {
+		return value, err
+	}
+
+	var patterns []Pattern
+	var guards, bodies []Expr
+	for {
+		pattern, err := p.pattern()
+		if err != nil {
+			return value, err
+		}
+
+		var guard Expr
+		if p.match(tok.IF) {
+			guard, err = p.Expression()
+			if err != nil {
+				return guard, err
+			}
+		}
+
+		_, err = p.consume(tok.FAT_ARROW, "Expect '=>' after match pattern.")
+		if err != nil {
+			return value, err
+		}
+		body, err := p.Expression()
+		if err != nil {
+			return body, err
+		}
+
+		patterns = append(patterns, pattern)
+		guards = append(guards, guard)
+		bodies = append(bodies, body)
+		if !p.match(tok.COMMA) {
+			break
+		}
+	}
+
+	_, err = p.consume(tok.RIGHT_BRACE, "Expect '}' after match arms.")
+	if err != nil {
+		return value, err
+	}
+	p.node(MATCH, mark)
+	return NewMatch(keyword, value, patterns, guards, bodies), nil
+}
+
+// pattern        → alternative ( "|" alternative )*
+func (p *Parser) pattern() (Pattern, error) {
+	// Patterns nest without going through unary
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	mark := p.mark()
+	alternative, err := p.alternative()
+	if err != nil || !p.check(tok.PIPE) {
+		return alternative, err
+	}
+
+	alternatives := []Pattern{alternative}
+	for p.match(tok.PIPE) {
+		alternative, err = p.alternative()
+		if err != nil {
+			return alternative, err
+		}
+		alternatives = append(alternatives, alternative)
+	}
+
+	p.node(ALTERNATIVES, mark)
+	return NewAlternatives(alternatives), nil
+}
+
+// alternative    → "_" | IDENTIFIER | instance | sequence | mapping | constant
+// instance       → IDENTIFIER "(" fields? ")"
+// sequence       → "[" fields? "]"
+// mapping        → "{" ( entry ( "," entry )* )? "}"
+// entry          → IDENTIFIER | constant ":" pattern
+func (p *Parser) alternative() (Pattern, error) {
+	mark := p.mark()
+	if p.match(tok.IDENTIFIER) {
+		name := p.previous()
+		if p.match(tok.LEFT_PAREN) {
+			fields, err := p.patterns(tok.RIGHT_PAREN, "Expect ')' after instance fields.")
+			if err != nil {
+				return nil, err
+			}
+			p.node(INSTANCE, mark)
+			return NewInstance(name, fields), nil
+		}
+		if name.Lexeme == "_" {
+			p.node(WILDCARD, mark)
+			return NewWildcard(name), nil
+		}
+		p.node(BINDING, mark)
+		return NewBinding(name), nil
+	}
+
+	if p.match(tok.LEFT_BRACKET) {
+		elements, err := p.patterns(tok.RIGHT_BRACKET, "Expect ']' after list pattern.")
+		if err != nil {
+			return nil, err
+		}
+		p.node(SEQUENCE, mark)
+		return NewSequence(elements), nil
+	}
+
+	if p.match(tok.LEFT_BRACE) {
+		keys, values := []Expr{}, []Pattern{}
+		if !p.check(tok.RIGHT_BRACE) {
+			for {
+				// A lone name binds the value under its own name
+				if p.check(tok.IDENTIFIER) {
+					entryMark := p.mark()
+					name := p.advance()
+					p.node(BINDING, entryMark)
+					keys = append(keys, NewLiteral(name.Lexeme))
+					values = append(values, NewBinding(name))
+					if !p.match(tok.COMMA) {
+						break
+					}
+					continue
+				}
+
+				key, err := p.constant()
+				if err != nil {
+					return nil, err
+				}
+				_, err = p.consume(tok.COLON, "Expect ':' after map pattern key.")
+				if err != nil {
+					return nil, err
+				}
+				value, err := p.pattern()
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, key)
+				values = append(values, value)
+				if !p.match(tok.COMMA) {
+					break
+				}
+			}
+		}
+		_, err := p.consume(tok.RIGHT_BRACE, "Expect '}' after map pattern.")
+		if err != nil {
+			return nil, err
+		}
+		p.node(MAPPING, mark)
+		return NewMapping(keys, values), nil
+	}
+
+	constant, err := p.constant()
+	if err != nil {
+		return nil, err
+	}
+	return NewConstant(constant.value), nil
+}
+
+// fields         → field ( "," field )*
+// field          → "..." IDENTIFIER | pattern
+func (p *Parser) patterns(closing tok.TokenType, msg string) ([]Pattern, error) {
+	patterns := []Pattern{}
+	rest := false
+	if !p.check(closing) {
+		for {
+			mark := p.mark()
+			if p.match(tok.ELLIPSIS) {
+				if rest {
+					return patterns, ParseError{
+						token: p.previous(),
+						msg:   "Only one rest element is allowed.",
+					}
+				}
+				rest = true
+				name, err := p.consume(tok.IDENTIFIER, "Expect name after '...'.")
+				if err != nil {
+					return patterns, err
+				}
+				p.node(REST, mark)
+				patterns = append(patterns, NewRest(name))
+				if !p.match(tok.COMMA) {
+					break
+				}
+				continue
+			}
+
+			pattern, err := p.pattern()
+			if err != nil {
+				return patterns, err
+			}
+			patterns = append(patterns, pattern)
+			if !p.match(tok.COMMA) {
+				break
+			}
+		}
+	}
+	_, err := p.consume(closing, msg)
+	return patterns, err
+}
+
+// constant       → NUMBER | "-" NUMBER | STRING | "true" | "false" | "nil"
+func (p *Parser) constant() (Literal, error) {
+	mark := p.mark()
+	if p.match(tok.FALSE, tok.TRUE, tok.NIL, tok.NUMBER, tok.STRING) {
+		p.node(CONSTANT, mark)
+		return NewLiteral(literalValue(p.previous())), nil
+	}
+
+	if p.match(tok.MINUS) {
+		number, err := p.consume(tok.NUMBER, "Expect number after '-' in pattern.")
+		if err != nil {
+			return Literal{}, err
+		}
+		p.node(CONSTANT, mark)
+		// The scanner only makes float64 numbers
+		value, _ := number.Literal.(float64)
+		return NewLiteral(-value), nil
+	}
+
+	return Literal{}, ParseError{
+		token: p.peek(),
+		msg:   "Expect pattern.",
+	}
+}
+
 func (p *Parser) consume(t tok.TokenType, m string) (tok.Token, error) {
 	if p.check(t) {
 		return p.advance(), nil
@@ -132,6 +2164,7 @@ func (p *Parser) consume(t tok.TokenType, m string) (tok.Token, error) {
 }
 
 func (p *Parser) term() (Expr, error) {
+	mark := p.mark()
 	expr, err := p.factor()
 	if err != nil {
 		return expr, err
@@ -144,29 +2177,43 @@ func (p *Parser) term() (Expr, error) {
 			return right, err
 		}
 		expr = NewBinary(expr, operator, right)
+		p.node(BINARY, mark)
 	}
 
 	return expr, nil
 }
 
 func (p *Parser) factor() (Expr, error) {
+	mark := p.mark()
 	expr, err := p.Unary()
 	if err != nil {
 		return expr, err
 	}
 
-	for p.match(tok.SLASH, tok.STAR) {
+	for p.match(tok.SLASH, tok.STAR, tok.SLASH_SLASH, tok.PERCENT) {
 		operator := p.previous()
 		right, err := p.Unary()
 		if err != nil {
 			return right, err
 		}
 		expr = NewBinary(expr, operator, right)
+		p.node(BINARY, mark)
 	}
 
 	return expr, nil
 }
 
+// skip goes past the tokens of a grouping parsed before, its '(' matched
+func (p *Parser) skip(g grouping) Expr {
+	for i := 1; i < g.tokens; i++ {
+		p.advance()
+	}
+	if p.depth+g.depth > p.deepest {
+		p.deepest = p.depth + g.depth
+	}
+	return g.expr
+}
+
 func (p *Parser) match(types ...tok.TokenType) bool {
 	for _, t := range types {
 		if p.check(t) {
@@ -187,21 +2234,88 @@ func (p *Parser) check(t tok.TokenType) bool {
 
 func (p *Parser) advance() tok.Token {
 	if !p.isAtEnd() {
-		p.current++
+		if p.tree != nil {
+			p.tree.token(p.consumed, p.currentToken)
+		}
+		p.previousToken = p.currentToken
+		p.currentToken = p.next()
+		p.consumed++
 	}
 	return p.previous()
 }
 
+// next pulls the token after the current one, those looked ahead first
+func (p *Parser) next() tok.Token {
+	if len(p.ahead) > 0 {
+		t := p.ahead[0]
+		p.ahead = p.ahead[1:]
+		return t
+	}
+	return p.source.NextToken()
+}
+
+// lookAhead returns the n-th token past the current one without consuming
+// anything, EOF when the source ends before
+func (p *Parser) lookAhead(n int) tok.Token {
+	if p.isAtEnd() {
+		return p.peek()
+	}
+	for len(p.ahead) < n {
+		if len(p.ahead) > 0 && p.ahead[len(p.ahead)-1].TokenType == tok.EOF {
+			return p.ahead[len(p.ahead)-1]
+		}
+		p.ahead = append(p.ahead, p.source.NextToken())
+	}
+	return p.ahead[n-1]
+}
+
+// pulled returns how many tokens were pulled from the source so far, looked
+// ahead ones included
+func (p *Parser) pulled() int {
+	return p.consumed + 1 + len(p.ahead)
+}
+
+// mark returns the index of the next token, for node to wrap what comes next
+func (p *Parser) mark() int {
+	return p.consumed
+}
+
+func (p *Parser) node(kind SyntaxKind, mark int) {
+	if p.tree != nil {
+		p.tree.node(kind, mark)
+	}
+}
+
+// enter goes one level of nesting deeper, leave must follow when it succeeds
+func (p *Parser) enter() error {
+	p.depth++
+	if p.depth > p.deepest {
+		p.deepest = p.depth
+	}
+	if p.depth > maxDepth {
+		p.depth--
+		return ParseError{
+			token: p.peek(),
+			msg:   "Expression nested too deeply.",
+		}
+	}
+	return nil
+}
+
+func (p *Parser) leave() {
+	p.depth--
+}
+
 func (p *Parser) isAtEnd() bool {
 	return p.peek().TokenType == tok.EOF
 }
 
 func (p *Parser) peek() tok.Token {
-	return p.tokens[p.current]
+	return p.currentToken
 }
 
 func (p *Parser) previous() tok.Token {
-	return p.tokens[p.current-1]
+	return p.previousToken
 }
 
 func (p *Parser) synchronize() {
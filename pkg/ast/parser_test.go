@@ -0,0 +1,590 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cedricmar/bazic/pkg/scanner"
+	tok "github.com/cedricmar/bazic/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(src string) (Expr, error) {
+	sc := scanner.NewScanner(src)
+	p := NewParser(sc.ScanTokens())
+	return p.Parse()
+}
+
+func program(src string) ([]Stmt, error) {
+	sc := scanner.NewScanner(src)
+	p := NewParser(sc.ScanTokens())
+	return p.Program()
+}
+
+// printProgram prints each statement on its own line
+func printProgram(stmts []Stmt) string {
+	var printed []string
+	for _, stmt := range stmts {
+		printed = append(printed, NewPrinter().PrintStmt(stmt))
+	}
+	return strings.Join(printed, "\n")
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"[]":                                     "(list)",
+		"[1, \"a\", [true]]":                     "(list 1 a (list true))",
+		"[1, 2][0]":                              "(index (list 1 2) 0)",
+		"[1, 2][-1] == 2":                        "(== (index (list 1 2) (- 1)) 2)",
+		"-[[1]][0][0]":                           "(- (index (index (list (list 1)) 0) 0))",
+		"[1, 2, 3][1:2]":                         "(slice (list 1 2 3) 1 2)",
+		"[1, 2, 3][:-1]":                         "(slice (list 1 2 3) _ (- 1))",
+		"[1, 2, 3][1:]":                          "(slice (list 1 2 3) 1 _)",
+		"[1][:]":                                 "(slice (list 1) _ _)",
+		"[1][0] = [2][0] = 3":                    "(set (list 1) 0 (set (list 2) 0 3))",
+		"[1][0] = 1 + 2":                         "(set (list 1) 0 (+ 1 2))",
+		"([1])[0]":                               "(index (group (list 1)) 0)",
+		"{}":                                     "(map)",
+		"{\"a\": 1, 2: [3]}":                     "(map (: a 1) (: 2 (list 3)))",
+		"{nil: {true: 1}}[nil]":                  "(index (map (: nil (map (: true 1)))) nil)",
+		"{\"a\": 1}[\"a\"] = 2":                  "(set (map (: a 1)) a 2)",
+		"[1][0] += 2":                            "(+= (index (list 1) 0) 2)",
+		"[1][0] -= [2][0] *= 3":                  "(-= (index (list 1) 0) (*= (index (list 2) 0) 3))",
+		"[1][0] /= 1 + 2":                        "(/= (index (list 1) 0) (+ 1 2))",
+		"[1][0] %= 2":                            "(%= (index (list 1) 0) 2)",
+		"++[1][0]":                               "(++ (index (list 1) 0))",
+		"-[1][0]--":                              "(- (post-- (index (list 1) 0)))",
+		"[1][0]++ * 2":                           "(* (post++ (index (list 1) 0)) 2)",
+		"--[[1]][0][0] ** 2":                     "(** (-- (index (index (list (list 1)) 0) 0)) 2)",
+		"match 1 { 1 | 2 => \"a\", _ => \"b\" }": "(match 1 (=> (| 1 2) a) (=> _ b))",
+		"match [1, 2] { [x, y] => x + y, [] => 0 }":           "(match (list 1 2) (=> (list x y) (+ x y)) (=> (list) 0))",
+		"match {} { {\"k\": v} => v, {-1: [_]} => 0 }":        "(match (map) (=> (map (: k v)) v) (=> (map (: -1 (list _))) 0))",
+		"match p { {name, \"age\": 1} => name, {} => nil }":   "(match p (=> (map (: name name) (: age 1)) name) (=> (map) nil))",
+		"match p { Point(x, y) if x > 0 => x, Point() => 0 }": "(match p (=> (Point x y) (if (> x 0)) x) (=> (Point) 0))",
+		"match x { nil | true | \"s\" | -2.5 => 1 }":          "(match x (=> (| nil true s -2.5) 1))",
+		"match x { _ => match y { _ => 1 } }":                 "(match x (=> _ (match y (=> _ 1))))",
+		"x = y = 1":                                           "(assign x (assign y 1))",
+		"x += 1":                                              "(+= x 1)",
+		"x--":                                                 "(post-- x)",
+		"[a, b] = [b, a]":                                     "(assign (list a b) (list b a))",
+		"[head, ...tail] = [1, ...xs]":                        "(assign (list head (... tail)) (list 1 (... xs)))",
+		"{\"name\": n, 1: [_, a]} = p":                        "(assign (map (: name n) (: 1 (list _ a))) p)",
+		"match xs { [h, ...t] => t, P(x, ..._) => x }":        "(match xs (=> (list h (... t)) t) (=> (P x (... _)) x))",
+		"f()":                               "(call f)",
+		"f(1, ...xs)(2)[0]":                 "(index (call (call f 1 (... xs)) 2) 0)",
+		"connect(host: \"x\", port: 80)":    "(call connect (: host x) (: port 80))",
+		"greet(name, greeting: [1][0] + 2)": "(call greet name (: greeting (+ (index (list 1) 0) 2)))",
+		"-f(1) ** 2":                        "(- (** (call f 1) 2))",
+		"f(x)[0] = 1":                       "(set (call f x) 0 1)",
+		"this.w * this.h":                   "(* (. this w) (. this h))",
+		"a.b.c = 1":                         "(.= (. a b) c 1)",
+		"a.b(1).c[0] += 2":                  "(+= (index (. (call (. a b) 1) c) 0) 2)",
+		"p.x++":                             "(post++ (. p x))",
+		"1.5.x":                             "(. 1.5 x)",
+		"spawn f(x)":                        "(spawn (call f x))",
+		"await f(x).y + 1":                  "(+ (await (. (call f x) y)) 1)",
+		"x = await await spawn f() * 2":     "(assign x (* (await (await (spawn (call f)))) 2))",
+		"-await p":                          "(- (await p))",
+		"t = spawn jobs[0].run(1, 2)":       "(assign t (spawn (call (. (index jobs 0) run) 1 2)))",
+		"!spawn f() == spawn g()()":         "(== (! (spawn (call f))) (spawn (call (call g))))",
+	}
+
+	for src, want := range tests {
+		expr, err := parse(src)
+		if assert.Nil(t, err, src) {
+			assert.Equal(t, want, NewPrinter().Print(expr), src)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	srcs := []string{
+		"",
+		"(1 + 2",
+		"1 2",
+		"1 +",
+		")",
+		strings.Repeat("(", maxDepth+1) + "1" + strings.Repeat(")", maxDepth+1),
+		strings.Repeat("- ", maxDepth+1) + "1",
+		"[1, 2",
+		"[1,]",
+		"[1][0",
+		"[1][0:1",
+		"1 = 2",
+		"[1][0:1] = 2",
+		"{1}",
+		"{1: 2",
+		"{1: 2,}",
+		"{: 2}",
+		"1 += 2",
+		"(1) = 2",
+		"[1][0:1] -= 2",
+		"1++",
+		"--1",
+		"++[1][0]++",
+		"[1][0]++ = 2",
+		"match 1 {}",
+		"match 1 { _ }",
+		"match 1 { _ => 1",
+		"match 1 { _ => 1, }",
+		"match 1 { 1 + 2 => 3 }",
+		"match 1 { Point(x => 1 }",
+		"match 1 { {x: 1} => 1 }",
+		"match 1 { _ if => 1 }",
+		"match 1 " + strings.Repeat("[", maxDepth+1),
+		"[1] = 2",
+		"[a + 1] = 2",
+		"{a: b} = 1",
+		"[a, ...b, ...c] = 1",
+		"[...a[0]] = 1",
+		"[...] = 1",
+		"...a",
+		"a..b",
+		"match 1 { [...1] => 1 }",
+		"match 1 { [...a, ...b] => 1 }",
+		"f(1",
+		"f(1,)",
+		"f(a: 1, 2)",
+		"f(a: 1, ...b)",
+		"f(a: 1, a: 2)",
+		"f((a): 1)",
+		"f(x) = 1",
+		"f(x)++",
+		"a.",
+		"a.1",
+		"a.(b)",
+		"[a.b] = 1",
+		"this = 1",
+		"spawn",
+		"spawn f",
+		"spawn f()[0]",
+		"spawn (f())",
+		"spawn f(",
+		"await",
+		"await )",
+		strings.Repeat("await ", maxDepth+1) + "p",
+	}
+
+	for _, src := range srcs {
+		_, err := parse(src)
+		assert.IsType(t, ParseError{}, err, src)
+	}
+}
+
+func TestProgram(t *testing.T) {
+	tests := map[string]string{
+		"":                                     "",
+		"1 + 2":                                "(+ 1 2)",
+		"1; 2;":                                "1\n2",
+		"x = 1; x + 2":                         "(assign x 1)\n(+ x 2)",
+		"enum Color { Red, Green, Blue }":      "(enum Color Red Green Blue)",
+		"enum Shape { Circle(r), Rect(w, h) }": "(enum Shape (Circle r) (Rect w h))",
+		"enum Option { None, Some(value), Unit() }": "(enum Option None (Some value) (Unit))",
+		"enum E { A } E":                               "(enum E A)\nE",
+		"export enum E { A }":                          "(export (enum E A))",
+		"export fun add(a, b) { return a + b; }":       "(export (fun add (a b) (block (return (+ a b)))))",
+		"export var n: Number = 1;":                    "(export (var n Number 1))",
+		"export const limit = 10;":                     "(export (const limit 10))",
+		"export class C with T {}":                     "(export (class C (with T)))",
+		"export trait T { m(); }":                      "(export (trait T (fun m ())))",
+		"fun f() {} f()":                               "(fun f () (block))\n(call f)",
+		"fun f(x) { fun g() { return x; } return g; }": "(fun f (x) (block (fun g () (block (return x))) (return g)))",
+		"import \"lib/strings.bz\" as str;":            "(import lib/strings.bz str)",
+		"import \"x.bz\";":                             "(import x.bz)",
+		"import { a, b } from \"x.bz\"; a(b)":          "(import x.bz (a b))\n(call a b)",
+		"for (x in xs) f(x);":                          "(for (x) xs (call f x))",
+		"for (k, v in m) { f(k); g(v); }":              "(for (k v) m (block (call f k) (call g v)))",
+		"for (c in \"abc\") {}":                        "(for (c) abc (block))",
+		"for (x in xs) for (y in x) y":                 "(for (x) xs (for (y) x y))",
+		"while (x < 3) x++;":                           "(while (< x 3) (post++ x))",
+		"while (true) { break; }":                      "(while true (block (break)))",
+		"for (x in xs) { y = x; continue; }":           "(for (x) xs (block (assign y x) (continue)))",
+		"trait Comparable { compare(other); lessThan(other) { return this.compare(other) < 0; } }": "(trait Comparable (fun compare (other)) (fun lessThan (other) (block (return (< (call (. this compare) other) 0)))))",
+		"trait Empty {}": "(trait Empty)",
+		"class Money with Comparable, Hashable { compare(other) { return this.cents - other.cents; } }": "(class Money (with Comparable Hashable) (fun compare (other) (block (return (- (. this cents) (. other cents))))))",
+		"class B < A { init() {} }":                                        "(class B (< A) (fun init () (block)))",
+		"class C < A with T {}":                                            "(class C (< A) (with T))",
+		"class D { m(a, b) { for (x in a) { return; } } }":                 "(class D (fun m (a b) (block (for (x) a (block (return))))))",
+		"while (x) { class E { m() { while (y) break; } } }":               "(while x (block (class E (fun m () (block (while y (break)))))))",
+		"const limit = 10; limit * 2":                                      "(const limit 10)\n(* limit 2)",
+		"var [a, b] = pair();":                                             "(var (list a b) (call pair))",
+		"var {name, age} = person;":                                        "(var (map (: name name) (: age age)) person)",
+		"var [head, ...tail] = xs;":                                        "(var (list head (... tail)) xs)",
+		"var {\"p\": [x, _], name} = v;":                                   "(var (map (: p (list x _)) (: name name)) v)",
+		"export var [a, b] = [1, 2];":                                      "(export (var (list a b) (list 1 2)))",
+		"var n = 1;":                                                       "(var n 1)",
+		"var n: Number = 1; n":                                             "(var n Number 1)\nn",
+		"var xs: List<Number> = [];":                                       "(var xs List<Number> (list))",
+		"var m: Map<String, List<Number>> = {};":                           "(var m Map<String, List<Number>> (map))",
+		"var m: List<List<List<Number>>> = [];":                            "(var m List<List<List<Number>>> (list))",
+		"var m: Map<List<Number>, Bool> = {};":                             "(var m Map<List<Number>, Bool> (map))",
+		"throw [\"bad\", 1];":                                              "(throw (list bad 1))",
+		"try { f(); } catch (e) { g(e); }":                                 "(try (block (call f)) (catch e (block (call g e))))",
+		"try { f(); } finally { close(); }":                                "(try (block (call f)) (finally (block (call close))))",
+		"try {} catch (e) { throw e; } finally {}":                         "(try (block) (catch e (block (throw e))) (finally (block)))",
+		"while (x) try { break; } finally { x = nil; }":                    "(while x (try (block (break)) (finally (block (assign x nil)))))",
+		"outer: while (a) for (x in xs) break outer;":                      "(label outer (while a (for (x) xs (break outer))))",
+		"outer: for (x in xs) { inner: for (y in x) { continue outer; } }": "(label outer (for (x) xs (block (label inner (for (y) x (block (continue outer)))))))",
+		"for (var i = 0; i < 3; i++) {}":                                   "(loop (var i 0) (< i 3) (post++ i) (block))",
+		"for (i = 0; i < n; i += 1) f(i);":                                 "(loop (assign i 0) (< i n) (+= i 1) (call f i))",
+		"for (var i = 0; i < 3; i++) { continue; }":                        "(loop (var i 0) (< i 3) (post++ i) (block (continue)))",
+		"fun f(a: String, b: List<Number>): Bool { return true; }":         "(fun f (a:String b:List<Number>) (-> Bool) (block (return true)))",
+		"fun f(m: Map<String, List<Number>>, n) {}":                        "(fun f (m:Map<String, List<Number>> n) (block))",
+		"fun greet(name, greeting = \"hi\") {}":                            "(fun greet (name greeting=hi) (block))",
+		"class C { static m() {} }":                                        "(class C (static fun m () (block)))",
+		"class Rect { w = 0; h: Number = 0; static count = 0; static unit; area { return this.w * this.h; } }": "(class Rect (field w 0) (field h Number 0) (static field count 0) (static field unit) (get area (block (return (* (. this w) (. this h))))))",
+		"class C { set area(v) { this._area = v; } area { return this._area; } }":                              "(class C (set area (v) (block (.= this _area v))) (get area (block (return (. this _area)))))",
+		"class C { static set x(v) {} static x { return 1; } x() {} }":                                         "(class C (static set x (v) (block)) (static get x (block (return 1))) (fun x () (block)))",
+		"class C { set(x) {} get; static async m() {} }":                                                       "(class C (field get) (fun set (x) (block)) (static async fun m () (block)))",
+		"class C { _secret = 1; _hide() { return this._secret; } }":                                            "(class C (field _secret 1) (fun _hide () (block (return (. this _secret)))))",
+		"trait T { area { return 0; } set area(v); }":                                                          "(trait T (get area (block (return 0))) (set area (v)))",
+		"async fun fetch(url) { return await get(url); }":                                                      "(async fun fetch (url) (block (return (await (call get url)))))",
+		"export async fun f() {}":                                                                              "(export (async fun f () (block)))",
+		"class C { async load(): List { return []; } save() {} }":                                              "(class C (async fun load () (-> List) (block (return (list)))) (fun save () (block)))",
+		"trait T { async m(); }":                                                                               "(trait T (async fun m ()))",
+		"fun sum(...nums) {}":                                                                                  "(fun sum (...nums) (block))",
+		"fun f(a, b: Number = 1 + 2, ...c: List<Number>) {}":                                                   "(fun f (a b:Number=(+ 1 2) ...c:List<Number>) (block))",
+		"fun f(a = [], b = {}) {}":                                                                             "(fun f (a=(list) b=(map)) (block))",
+		"trait T { m(a = 1); }":                                                                                "(trait T (fun m (a=1)))",
+		"fun f(): Nil {}":                                                                                      "(fun f () (-> Nil) (block))",
+		"trait T { m(x: Number): String; }":                                                                    "(trait T (fun m (x:Number) (-> String)))",
+		"class C { m(a: C): C { return a; } }":                                                                 "(class C (fun m (a:C) (-> C) (block (return a))))",
+		"for (;;) break;":                                                                                      "(loop () () () (break))",
+		"for (; x;) {}":                                                                                        "(loop () x () (block))",
+		"outer: for (var i = 0;; i++) for (x in xs) continue outer;":                                           "(label outer (loop (var i 0) () (post++ i) (for (x) xs (continue outer))))",
+		"fun g() { yield; }":                                                                                   "(fun g () (block (yield)))",
+		"fun g() { x = yield y = 1 + 2; }":                                                                     "(fun g () (block (assign x (yield (assign y (+ 1 2))))))",
+		"fun g() { f(yield, [yield], yield yield 1); }":                                                        "(fun g () (block (call f (yield) (list (yield)) (yield (yield 1)))))",
+		"fun g() { match (yield) { _ => yield }; }":                                                            "(fun g () (block (match (group (yield)) (=> _ (yield)))))",
+		"class C { items() { for (x in this.xs) yield x; } }":                                                  "(class C (fun items () (block (for (x) (. this xs) (yield x)))))",
+		"{ x = 1; }":                        "(block (assign x 1))",
+		"{ { f(); } { g(); } }":             "(block (block (call f)) (block (call g)))",
+		"{ outer: while (a) break outer; }": "(block (label outer (while a (break outer))))",
+		"{ var x = 1; } x":                  "(block (var x 1))\nx",
+		"{}":                                "(map)",
+		"{\"a\": 1}[\"a\"];":                "(index (map (: a 1)) a)",
+		"{x: 1};":                           "(map (: x 1))",
+		"{\"name\": n} = p;":                "(assign (map (: name n)) p)",
+		"while (x) { { break; } }":          "(while x (block (block (break))))",
+	}
+
+	for src, want := range tests {
+		stmts, err := program(src)
+		if assert.Nil(t, err, src) {
+			assert.Equal(t, want, printProgram(stmts), src)
+		}
+	}
+
+	srcs := []string{
+		"1 2",
+		"1; 2; )",
+		"1;;",
+		"enum",
+		"enum {}",
+		"enum E {}",
+		"enum E { A,  }",
+		"enum E { A, A }",
+		"enum E { A(1) }",
+		"enum E { A(x }",
+		"enum E { A",
+		"export",
+		"export 1",
+		"export export enum E { A }",
+		"export import \"x.bz\";",
+		"export x = 1;",
+		"export for (x in xs) {}",
+		"for (x in xs) { export var y = x; }",
+		"fun f() { export const c = 1; }",
+		"class C { m() { export fun g() {} } }",
+		"fun",
+		"fun () {}",
+		"fun f {}",
+		"fun f();",
+		"fun f(a, a) {}",
+		"fun f(a: Number, a) {}",
+		"fun f(a = 1, b) {}",
+		"async",
+		"class C { static }",
+		"class C { static static m() {} }",
+		"class C { x }",
+		"class C { x = 1 }",
+		"class C { x: = 1; }",
+		"class C { x; x; }",
+		"class C { x; x() {} }",
+		"class C { x { } x { } }",
+		"class C { set x(v) {} set x(v) {} }",
+		"class C { set x() {} }",
+		"class C { set x(a, b) {} }",
+		"class C { set x(...a) {} }",
+		"class C { x {}; }",
+		"class C { async x; }",
+		"trait T { static m(); }",
+		"trait T { x; }",
+		"trait T { x = 1; }",
+		"static m() {}",
+		"fun f() { static x = 1; }",
+		"async f() {}",
+		"async async fun f() {}",
+		"fun async f() {}",
+		"async var x = 1;",
+		"class C { async async m() {} }",
+		"class C { async fun m() {} }",
+		"class C { async m() {} m() {} }",
+		"fun f(...a, b) {}",
+		"fun f(...a,) {}",
+		"fun f(...a = []) {}",
+		"fun f(... a, ...b) {}",
+		"fun f(...) {}",
+		"fun f(a =) {}",
+		"fun f(a = 1 {}",
+		"fun f(...a, ...a) {}",
+		"fun f(a:) {}",
+		"fun f(a: 1) {}",
+		"fun f(a: List<Number>>) {}",
+		"fun f(): {}",
+		"fun f(): List<List<Number>>> {}",
+		"fun f() Number {}",
+		"trait T { m(): Number }",
+		"fun f() { break; }",
+		"while (x) { fun f() { continue; } }",
+		"import",
+		"import x;",
+		"import \"x.bz\"",
+		"import \"x.bz\" as;",
+		"import \"x.bz\" as \"y\";",
+		"import {} from \"x.bz\";",
+		"import { a, } from \"x.bz\";",
+		"import { a } \"x.bz\";",
+		"import { a } from x;",
+		"import { a, a } from \"x.bz\";",
+		"import { a, b, a } from \"x.bz\";",
+		"{ import \"x.bz\"; }",
+		"fun f() { import { a } from \"x.bz\"; }",
+		"while (x) import \"x.bz\";",
+		"class C { m() { import \"x.bz\" as x; } }",
+		"for x in xs {}",
+		"for (1 in xs) {}",
+		"for (x xs) {}",
+		"for (x, x in xs) {}",
+		"for (x, y, z in xs) {}",
+		"for (x in xs {}",
+		"for (x in xs)",
+		"for (x in xs) { f(x) }",
+		"for (x in xs) { f(x);",
+		"for (var i = 0 i < 3; i++) {}",
+		"for (var i = 0; i < 3 i++) {}",
+		"for (var i = 0; i < 3; i++ {}",
+		"for (var i; i < 3; i++) {}",
+		"for (x = 1) {}",
+		"for (i in xs; i;) {}",
+		"for (;;)",
+		"for (;; i++) { export var y = 1; }",
+		"while x {}",
+		"while (x {}",
+		"break;",
+		"continue;",
+		"x; break",
+		"while (x) break",
+		"while (x) break y;",
+		"while (x) continue 1;",
+		"a: while (x) { b: while (y) break c; }",
+		"a: while (x) { a: while (y) {} }",
+		"a: x;",
+		"a: { }",
+		"yield",
+		"yield 1;",
+		"x = yield;",
+		"while (x) { yield x; }",
+		"class C { static n = yield; }",
+		"fun f(a = yield) {}",
+		"fun g() { 1 + yield 2; }",
+		"fun g() { -yield; }",
+		"fun g() { " + strings.Repeat("yield ", maxDepth+1) + "}",
+		"{ x = 1 }",
+		"{ x = 1;",
+		"{ export var x = 1; }",
+		"{ break; }",
+		"(a): while (x) {}",
+		"a.b: while (x) {}",
+		"for (x in xs) { enum E { A } } break;",
+		"throw;",
+		"throw 1",
+		"try {}",
+		"try f();",
+		"try {} catch {}",
+		"try {} catch (1) {}",
+		"try {} catch (e {}",
+		"try {} catch (e) f();",
+		"try {} finally",
+		"try {} finally {} catch (e) {}",
+		"catch (e) {}",
+		"try { break; } finally {}",
+		"var;",
+		"var n;",
+		"var n = 1",
+		"var 1 = 1;",
+		"var n: = 1;",
+		"var n: Number 1;",
+		"var n: List<> = [];",
+		"var n: List<Number = [];",
+		"var n: List<Number>> = [];",
+		"var n: List<List<Number>>> = [];",
+		"var n: List<Number,> = [];",
+		"var n: \"a\" = 1;",
+		"var [a, b];",
+		"var [a, b] = x",
+		"var [a, a] = x;",
+		"var {a, \"b\": [a]} = x;",
+		"var [a]: List = x;",
+		"var {a: b} = x;",
+		"var {a,} = x;",
+		"var [a, ...b, ...c] = x;",
+		"const;",
+		"const a;",
+		"const a = 1",
+		"const a: Number = 1;",
+		"const 1 = 1;",
+		"return;",
+		"while (x) return 1;",
+		"trait {}",
+		"trait T",
+		"trait T { m }",
+		"trait T { m() }",
+		"trait T { m(); m() {} }",
+		"trait T { m(a, a); }",
+		"trait T { m(a,); }",
+		"trait T { 1; }",
+		"class {}",
+		"class C",
+		"class C { m(); }",
+		"class C < {}",
+		"class C < C {}",
+		"class C with {}",
+		"class C with T, {}",
+		"class C with T, T {}",
+		"class C with T < A {}",
+		"class C { m() { return 1 } }",
+		"while (x) { class C { m() { break; } } }",
+		"a: while (x) { class C { m() { while (y) continue a; } } }",
+	}
+
+	for _, src := range srcs {
+		_, err := program(src)
+		assert.IsType(t, ParseError{}, err, src)
+	}
+}
+
+func TestParseMalformedTokens(t *testing.T) {
+	// No EOF
+	p := NewParser([]tok.Token{
+		tok.NewToken(tok.NUMBER, "1", 1.0, 1),
+		tok.NewToken(tok.PLUS, "+", nil, 1),
+		tok.NewToken(tok.NUMBER, "2", 2.0, 1),
+	})
+	expr, err := p.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, "(+ 1 2)", NewPrinter().Print(expr))
+
+	p = NewParser(nil)
+	_, err = p.Parse()
+	assert.IsType(t, ParseError{}, err)
+
+	p = NewParser([]tok.Token{tok.NewToken(tok.TokenType(-1), "?", nil, 1)})
+	_, err = p.Parse()
+	assert.IsType(t, ParseError{}, err)
+}
+
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"",
+		"1",
+		"(1 + 2) * -3 >= 4 / 5 == !true != nil",
+		"\"str\" + \"ing\"",
+		"((((1))))",
+		"(1 + 2",
+		"1 2",
+		"- - - !",
+		"# comment only",
+		"-2 ** -3 ** 4 // 5 % 6 << 7 >> 8 & 9 ^ 10 | ~11",
+		"\xff(",
+		"1; enum E { A(x), B }\nimport { a } from \"a.bz\"; export enum F { C } a",
+		"[1, [2, 3]][1][0:1] = [4][-1]",
+		"{\"a\": [1], 2: {}}[\"a\"]",
+		"match [1] { [x] | {\"k\": -1} if x => x, P(_, y) => y, _ => nil }",
+		"[a, [b, ...c]] = [...d, {\"e\": f}]",
+		"f(1, ...xs)(a: g(b), c: 2)[0]",
+		"this.a.b(c).d = this.e[0]--",
+		"fun g() { x = yield [yield, yield 1]; }",
+		"spawn a.b(c)(d) + spawn e()",
+		"await [await p, -await q[0]]",
+		"for (k, v in m) { for (x in v) { f(k, x); } }",
+		"a: while (x) { for (y in x) { continue a; } break; }",
+		"try { throw f(1); } catch (e) { g(e); } finally { h(); }",
+		"trait T { a(); b(x) { return this.a() + x; } } class C < B with T { a() { return; } }",
+		"var m: Map<String, List<List<Number>>> = {\"a\": [[1]]}; m = nil;",
+		"const c = [1]; [c, ...d] = e; c += 1",
+		"export fun f(a) { return a; } export var v: List<Number> = [f(1)]; export const c = v;",
+		"{ x = {}; { y: while (x) {} } {a: 1}; }",
+		"for (var i = 0; i < 3; i++) { for (;;) { continue; } } for (k, v in m) {}",
+		"fun f(a: String, b: List<Number>): Bool { return a == b[0]; } trait T { m(x: Map<String, Number>): Nil; }",
+		"fun greet(name, greeting = \"hi\", ...rest: List<String>) { return [greeting, name, ...rest]; }",
+		"export async fun f(a) { return await a; } class C { async m(x) { return await f(x); } }",
+		"class C { static n = 0; _w: Number = 1; area { return this._w; } set area(v) { this._w = v; } static make() { return C(); } }",
+		"var [a, {name, \"k\": [b, ...c]}] = f(); match a { {name, age} => age, _ => nil }",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		expr, err := parse(src)
+		if err == nil {
+			NewPrinter().Print(expr)
+			c := NewChecker()
+			c.Check(expr)
+		}
+
+		sc := scanner.NewScanner(src)
+		sc.KeepTrivia = true
+		p := NewSourceParser(&sc)
+		tree, treeErr := p.ParseTree()
+		assert.Equal(t, src, tree.String())
+		if err == nil {
+			derived, err := tree.Expr()
+			assert.Nil(t, err)
+			assert.Equal(t, NewPrinter().Print(expr), NewPrinter().Print(derived))
+		}
+
+		stmts, err := program(src)
+		assert.Equal(t, err == nil, treeErr == nil)
+		if err == nil {
+			c := NewChecker()
+			c.CheckProgram(stmts)
+			derived, err := tree.Stmts()
+			assert.Nil(t, err)
+			assert.Equal(t, printProgram(stmts), printProgram(derived))
+		}
+	})
+}
+
+// FuzzParseTokens feeds the parser tokens no scanner would make: any type,
+// EOF anywhere or nowhere
+func FuzzParseTokens(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{byte(tok.NUMBER), byte(tok.PLUS), byte(tok.NUMBER)})
+	f.Add([]byte{byte(tok.LEFT_PAREN), byte(tok.STRING), byte(tok.EOF), byte(tok.RIGHT_PAREN)})
+	f.Add([]byte{byte(tok.BANG), byte(tok.IDENTIFIER), 255})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tokens := make([]tok.Token, len(data))
+		for i, b := range data {
+			tokens[i] = tok.NewToken(tok.TokenType(b), "x", nil, i+1)
+		}
+
+		p := NewParser(tokens)
+		expr, err := p.Parse()
+		if err == nil {
+			NewPrinter().Print(expr)
+		}
+	})
+}
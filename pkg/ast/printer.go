@@ -3,6 +3,9 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"strings"
+
+	tok "github.com/cedricmar/bazic/pkg/token"
 )
 
 // Printer is a "pretty printer" for an AST
@@ -16,14 +19,50 @@ func (p Printer) Print(e Expr) string {
 	return fmt.Sprintf("%s", e.Accept(p))
 }
 
+func (p Printer) VisitAssignExpr(expr Assign) interface{} {
+	return p.list("assign", expr.name.Lexeme, p.Print(expr.value))
+}
+
+func (p Printer) VisitAwaitExpr(expr Await) interface{} {
+	return p.parenthesize("await", expr.value)
+}
+
 func (p Printer) VisitBinaryExpr(expr Binary) interface{} {
 	return p.parenthesize(expr.operator.Lexeme, expr.left, expr.right)
 }
 
+func (p Printer) VisitCallExpr(expr Call) interface{} {
+	return p.parenthesize("call", append([]Expr{expr.callee}, expr.arguments...)...)
+}
+
+func (p Printer) VisitCompoundExpr(expr Compound) interface{} {
+	return p.parenthesize(expr.operator.Lexeme, expr.target, expr.value)
+}
+
+func (p Printer) VisitDestructureExpr(expr Destructure) interface{} {
+	return p.list("assign", p.PrintPattern(expr.pattern), p.Print(expr.value))
+}
+
+func (p Printer) VisitGetExpr(expr Get) interface{} {
+	return p.list(".", p.Print(expr.object), expr.name.Lexeme)
+}
+
 func (p Printer) VisitGroupingExpr(expr Grouping) interface{} {
 	return p.parenthesize("group", expr.expression)
 }
 
+func (p Printer) VisitIndexExpr(expr Index) interface{} {
+	return p.parenthesize("index", expr.object, expr.index)
+}
+
+func (p Printer) VisitIndexSetExpr(expr IndexSet) interface{} {
+	return p.parenthesize("set", expr.object, expr.index, expr.value)
+}
+
+func (p Printer) VisitListLiteralExpr(expr ListLiteral) interface{} {
+	return p.parenthesize("list", expr.elements...)
+}
+
 func (p Printer) VisitLiteralExpr(expr Literal) interface{} {
 	if expr.value == nil {
 		return "nil"
@@ -31,10 +70,364 @@ func (p Printer) VisitLiteralExpr(expr Literal) interface{} {
 	return fmt.Sprintf("%v", expr.value)
 }
 
+func (p Printer) VisitMapLiteralExpr(expr MapLiteral) interface{} {
+	var buf bytes.Buffer
+
+	buf.WriteString("(map")
+	for i := range expr.keys {
+		buf.WriteString(" ")
+		buf.WriteString(fmt.Sprintf("%s", p.parenthesize(":", expr.keys[i], expr.values[i])))
+	}
+	buf.WriteString(")")
+
+	return buf.String()
+}
+
+func (p Printer) VisitMatchExpr(expr Match) interface{} {
+	arms := []string{p.Print(expr.value)}
+	for i, pattern := range expr.patterns {
+		arm := []string{p.PrintPattern(pattern)}
+		if expr.guards[i] != nil {
+			arm = append(arm, p.list("if", p.Print(expr.guards[i])))
+		}
+		arm = append(arm, p.Print(expr.bodies[i]))
+		arms = append(arms, p.list("=>", arm...))
+	}
+	return p.list("match", arms...)
+}
+
+func (p Printer) VisitNamedExpr(expr Named) interface{} {
+	return p.list(":", expr.name.Lexeme, p.Print(expr.value))
+}
+
+func (p Printer) VisitSetExpr(expr Set) interface{} {
+	return p.list(".=", p.Print(expr.object), expr.name.Lexeme, p.Print(expr.value))
+}
+
+func (p Printer) VisitSliceExpr(expr Slice) interface{} {
+	// Missing bounds are the ends of the list
+	start, end := Expr(NewLiteral("_")), Expr(NewLiteral("_"))
+	if expr.start != nil {
+		start = expr.start
+	}
+	if expr.end != nil {
+		end = expr.end
+	}
+	return p.parenthesize("slice", expr.object, start, end)
+}
+
+func (p Printer) VisitSpawnExpr(expr Spawn) interface{} {
+	return p.parenthesize("spawn", expr.call)
+}
+
+func (p Printer) VisitSpreadExpr(expr Spread) interface{} {
+	return p.parenthesize("...", expr.expression)
+}
+
+func (p Printer) VisitThisExpr(expr This) interface{} {
+	return "this"
+}
+
 func (p Printer) VisitUnaryExpr(expr Unary) interface{} {
 	return p.parenthesize(expr.operator.Lexeme, expr.right)
 }
 
+func (p Printer) VisitUpdateExpr(expr Update) interface{} {
+	if expr.prefix {
+		return p.parenthesize(expr.operator.Lexeme, expr.target)
+	}
+	return p.parenthesize("post"+expr.operator.Lexeme, expr.target)
+}
+
+func (p Printer) VisitVariableExpr(expr Variable) interface{} {
+	return expr.name.Lexeme
+}
+
+func (p Printer) VisitYieldExpr(expr Yield) interface{} {
+	if expr.value == nil {
+		return "(yield)"
+	}
+	return p.parenthesize("yield", expr.value)
+}
+
+func (p Printer) PrintPattern(pattern Pattern) string {
+	return fmt.Sprintf("%s", pattern.Accept(p))
+}
+
+func (p Printer) VisitAlternativesPattern(pattern Alternatives) interface{} {
+	return p.list("|", p.patterns(pattern.patterns)...)
+}
+
+func (p Printer) VisitBindingPattern(pattern Binding) interface{} {
+	return pattern.name.Lexeme
+}
+
+func (p Printer) VisitConstantPattern(pattern Constant) interface{} {
+	return p.Print(NewLiteral(pattern.value))
+}
+
+func (p Printer) VisitInstancePattern(pattern Instance) interface{} {
+	return p.list(pattern.class.Lexeme, p.patterns(pattern.fields)...)
+}
+
+func (p Printer) VisitMappingPattern(pattern Mapping) interface{} {
+	var entries []string
+	for i := range pattern.keys {
+		entries = append(entries, p.list(":", p.Print(pattern.keys[i]), p.PrintPattern(pattern.values[i])))
+	}
+	return p.list("map", entries...)
+}
+
+func (p Printer) VisitRestPattern(pattern Rest) interface{} {
+	return p.list("...", pattern.name.Lexeme)
+}
+
+func (p Printer) VisitSequencePattern(pattern Sequence) interface{} {
+	return p.list("list", p.patterns(pattern.elements)...)
+}
+
+func (p Printer) VisitWildcardPattern(pattern Wildcard) interface{} {
+	return "_"
+}
+
+func (p Printer) patterns(patterns []Pattern) []string {
+	var printed []string
+	for _, pattern := range patterns {
+		printed = append(printed, p.PrintPattern(pattern))
+	}
+	return printed
+}
+
+func (p Printer) PrintStmt(stmt Stmt) string {
+	return fmt.Sprintf("%s", stmt.Accept(p))
+}
+
+func (p Printer) VisitBlockStmt(stmt Block) interface{} {
+	return p.list("block", p.stmts(stmt.statements)...)
+}
+
+func (p Printer) VisitBreakStmt(stmt Break) interface{} {
+	return p.jump("break", stmt.label.Lexeme)
+}
+
+func (p Printer) VisitClassStmt(stmt Class) interface{} {
+	parts := []string{stmt.name.Lexeme}
+	if stmt.superclass.Lexeme != "" {
+		parts = append(parts, p.list("<", stmt.superclass.Lexeme))
+	}
+	if stmt.traits != nil {
+		parts = append(parts, p.list("with", p.tokens(stmt.traits)...))
+	}
+	for _, field := range stmt.fields {
+		parts = append(parts, p.field(field))
+	}
+	return p.list("class", append(parts, p.methods(stmt.methods)...)...)
+}
+
+func (p Printer) VisitConstStmt(stmt Const) interface{} {
+	return p.list("const", stmt.name.Lexeme, p.Print(stmt.initializer))
+}
+
+func (p Printer) VisitContinueStmt(stmt Continue) interface{} {
+	return p.jump("continue", stmt.label.Lexeme)
+}
+
+func (p Printer) VisitEnumStmt(stmt Enum) interface{} {
+	parts := []string{stmt.name.Lexeme}
+	for i, variant := range stmt.variants {
+		if stmt.fields[i] == nil {
+			parts = append(parts, variant.Lexeme)
+			continue
+		}
+		parts = append(parts, p.list(variant.Lexeme, p.tokens(stmt.fields[i])...))
+	}
+	return p.list("enum", parts...)
+}
+
+func (p Printer) VisitExportStmt(stmt Export) interface{} {
+	return p.list("export", p.PrintStmt(stmt.declaration))
+}
+
+func (p Printer) VisitExpressionStmt(stmt Expression) interface{} {
+	return p.Print(stmt.expression)
+}
+
+func (p Printer) VisitForStmt(stmt For) interface{} {
+	return p.list("for", "("+strings.Join(p.tokens(stmt.names), " ")+")", p.Print(stmt.iterable), p.PrintStmt(stmt.body))
+}
+
+func (p Printer) VisitFunctionStmt(stmt Function) interface{} {
+	params := []string{}
+	for _, param := range stmt.params {
+		params = append(params, p.parameter(param))
+	}
+	parts := []string{stmt.name.Lexeme}
+	// Getters go without parameters
+	if stmt.kind != GETTER_KIND {
+		parts = append(parts, "("+strings.Join(params, " ")+")")
+	}
+	if stmt.returns != nil {
+		parts = append(parts, p.list("->", stmt.returns.String()))
+	}
+	if stmt.body != nil {
+		parts = append(parts, p.PrintStmt(stmt.body))
+	}
+
+	keyword := "fun"
+	switch stmt.kind {
+	case GETTER_KIND:
+		keyword = "get"
+	case SETTER_KIND:
+		keyword = "set"
+	}
+	if stmt.async {
+		keyword = "async " + keyword
+	}
+	if stmt.static {
+		keyword = "static " + keyword
+	}
+	return p.list(keyword, parts...)
+}
+
+func (p Printer) VisitImportStmt(stmt Import) interface{} {
+	parts := []string{p.Print(NewLiteral(stmt.path.Literal))}
+	if stmt.names != nil {
+		parts = append(parts, "("+strings.Join(p.tokens(stmt.names), " ")+")")
+	} else if stmt.alias.Lexeme != "" {
+		parts = append(parts, stmt.alias.Lexeme)
+	}
+	return p.list("import", parts...)
+}
+
+func (p Printer) stmts(stmts []Stmt) []string {
+	var printed []string
+	for _, stmt := range stmts {
+		printed = append(printed, p.PrintStmt(stmt))
+	}
+	return printed
+}
+
+func (p Printer) VisitLabelStmt(stmt Label) interface{} {
+	return p.list("label", stmt.name.Lexeme, p.PrintStmt(stmt.loop))
+}
+
+func (p Printer) VisitLoopStmt(stmt Loop) interface{} {
+	// Missing clauses print empty
+	initializer, condition, increment := "()", "()", "()"
+	if stmt.initializer != nil {
+		initializer = p.PrintStmt(stmt.initializer)
+	}
+	if stmt.condition != nil {
+		condition = p.Print(stmt.condition)
+	}
+	if stmt.increment != nil {
+		increment = p.Print(stmt.increment)
+	}
+	return p.list("loop", initializer, condition, increment, p.PrintStmt(stmt.body))
+}
+
+func (p Printer) VisitReturnStmt(stmt Return) interface{} {
+	if stmt.value == nil {
+		return p.list("return")
+	}
+	return p.list("return", p.Print(stmt.value))
+}
+
+func (p Printer) VisitThrowStmt(stmt Throw) interface{} {
+	return p.list("throw", p.Print(stmt.value))
+}
+
+func (p Printer) VisitTraitStmt(stmt Trait) interface{} {
+	return p.list("trait", append([]string{stmt.name.Lexeme}, p.methods(stmt.methods)...)...)
+}
+
+func (p Printer) VisitTryStmt(stmt Try) interface{} {
+	parts := []string{p.PrintStmt(stmt.body)}
+	if stmt.handler != nil {
+		parts = append(parts, p.list("catch", stmt.name.Lexeme, p.PrintStmt(stmt.handler)))
+	}
+	if stmt.finally != nil {
+		parts = append(parts, p.list("finally", p.PrintStmt(stmt.finally)))
+	}
+	return p.list("try", parts...)
+}
+
+func (p Printer) VisitVarStmt(stmt Var) interface{} {
+	parts := []string{stmt.name.Lexeme}
+	if stmt.annotation != nil {
+		parts = append(parts, stmt.annotation.String())
+	}
+	return p.list("var", append(parts, p.Print(stmt.initializer))...)
+}
+
+func (p Printer) VisitVarPatternStmt(stmt VarPattern) interface{} {
+	return p.list("var", p.PrintPattern(stmt.pattern), p.Print(stmt.initializer))
+}
+
+func (p Printer) VisitWhileStmt(stmt While) interface{} {
+	return p.list("while", p.Print(stmt.condition), p.PrintStmt(stmt.body))
+}
+
+// field prints a field like a variable, static ones say so
+func (p Printer) field(field Field) string {
+	parts := []string{field.name.Lexeme}
+	if field.annotation != nil {
+		parts = append(parts, field.annotation.String())
+	}
+	if field.value != nil {
+		parts = append(parts, p.Print(field.value))
+	}
+	keyword := "field"
+	if field.static {
+		keyword = "static field"
+	}
+	return p.list(keyword, parts...)
+}
+
+func (p Printer) methods(methods []Function) []string {
+	var printed []string
+	for _, method := range methods {
+		printed = append(printed, p.PrintStmt(method))
+	}
+	return printed
+}
+
+func (p Printer) tokens(tokens []tok.Token) []string {
+	var printed []string
+	for _, t := range tokens {
+		printed = append(printed, t.Lexeme)
+	}
+	return printed
+}
+
+// parameter prints a parameter name, followed by its type when annotated
+// and its default value when it has one
+func (p Printer) parameter(param Parameter) string {
+	printed := param.name.Lexeme
+	if param.rest {
+		printed = "..." + printed
+	}
+	if param.annotation != nil {
+		printed += ":" + param.annotation.String()
+	}
+	if param.value != nil {
+		printed += "=" + p.Print(param.value)
+	}
+	return printed
+}
+
+func (p Printer) jump(keyword, label string) string {
+	if label == "" {
+		return p.list(keyword)
+	}
+	return p.list(keyword, label)
+}
+
+// list is parenthesize for parts already printed
+func (p Printer) list(name string, parts ...string) string {
+	return "(" + strings.Join(append([]string{name}, parts...), " ") + ")"
+}
+
 func (p Printer) parenthesize(name string, exprs ...Expr) interface{} {
 	var buf bytes.Buffer
 
@@ -21,3 +21,34 @@ func TestPrinter(t *testing.T) {
 
 	assert.Equal(t, "(* (- 123) (group 45.67))", Printer{}.Print(expr))
 }
+
+func TestPrinterPrecedence(t *testing.T) {
+	tests := map[string]string{
+		"7 % 3 * 2":         "(* (% 7 3) 2)",
+		"1 + 7 % 3":         "(+ 1 (% 7 3))",
+		"7 // 2 / 3":        "(/ (// 7 2) 3)",
+		"1 - 7 // 2":        "(- 1 (// 7 2))",
+		"2 ** 3 ** 2":       "(** 2 (** 3 2))",
+		"-2 ** 2":           "(- (** 2 2))",
+		"2 ** -1":           "(** 2 (- 1))",
+		"2 * 3 ** 2":        "(* 2 (** 3 2))",
+		"~1 ** 2":           "(~ (** 1 2))",
+		"[2][0] ** 2":       "(** (index (list 2) 0) 2)",
+		"~1 + 2":            "(+ (~ 1) 2)",
+		"1 << 2 + 3":        "(<< 1 (+ 2 3))",
+		"1 >> 2 << 3":       "(<< (>> 1 2) 3)",
+		"1 & 2 << 3":        "(& 1 (<< 2 3))",
+		"1 ^ 2 & 3":         "(^ 1 (& 2 3))",
+		"1 | 2 ^ 3":         "(| 1 (^ 2 3))",
+		"1 | 2 & 3 ^ 4 | 5": "(| (| 1 (^ (& 2 3) 4)) 5)",
+		"1 | 2 == 3":        "(== (| 1 2) 3)",
+		"1 & 2 < 3 >> 1":    "(< (& 1 2) (>> 3 1))",
+	}
+
+	for src, want := range tests {
+		expr, err := parse(src)
+		if assert.Nil(t, err, src) {
+			assert.Equal(t, want, Printer{}.Print(expr), src)
+		}
+	}
+}
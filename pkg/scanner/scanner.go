@@ -2,137 +2,421 @@ package scanner
 
 import (
 	"fmt"
+	"io"
 	"strconv"
+	"unicode/utf8"
 
 	tok "github.com/cedricmar/bazic/pkg/token"
 )
 
+// bufferSize is the number of bytes read from the source at once
+const bufferSize = 4096
+
+// maxEmptyReads is how many reads in a row may return nothing before giving
+// up on the source
+const maxEmptyReads = 100
+
 type Scanner struct {
-	source   string
-	tokens   []tok.Token
+	src      string // whole source when scanning a string, lexemes are slices of it
+	reader   io.Reader
+	buf      []byte // window over a reader's source, starting at the current token
+	end      int    // length of the source or window, whichever is scanned
+	eof      bool
+	token    tok.Token // last token found by scanToken
+	found    bool
+	dropped  int // bytes of the source before the window
+	offset   int // where the last token returned starts in the source
 	start    int
 	current  int
 	line     int
 	HadError bool
+	// KeepTrivia attaches whitespace and comments to the tokens around them
+	KeepTrivia bool
+	// SlashComments scans "//" as a comment like "#", as it was before floor
+	// division took it over
+	SlashComments bool
 }
 
 var keywords = map[string]tok.TokenType{
-	"and":    tok.AND,
-	"class":  tok.CLASS,
-	"else":   tok.ELSE,
-	"false":  tok.FALSE,
-	"for":    tok.FOR,
-	"fun":    tok.FUN,
-	"if":     tok.IF,
-	"nil":    tok.NIL,
-	"or":     tok.OR,
-	"print":  tok.PRINT,
-	"return": tok.RETURN,
-	"super":  tok.SUPER,
-	"this":   tok.THIS,
-	"true":   tok.TRUE,
-	"var":    tok.VAR,
-	"while":  tok.WHILE,
+	"and":      tok.AND,
+	"as":       tok.AS,
+	"async":    tok.ASYNC,
+	"await":    tok.AWAIT,
+	"break":    tok.BREAK,
+	"catch":    tok.CATCH,
+	"class":    tok.CLASS,
+	"const":    tok.CONST,
+	"continue": tok.CONTINUE,
+	"else":     tok.ELSE,
+	"enum":     tok.ENUM,
+	"export":   tok.EXPORT,
+	"false":    tok.FALSE,
+	"finally":  tok.FINALLY,
+	"for":      tok.FOR,
+	"from":     tok.FROM,
+	"fun":      tok.FUN,
+	"if":       tok.IF,
+	"import":   tok.IMPORT,
+	"in":       tok.IN,
+	"match":    tok.MATCH,
+	"nil":      tok.NIL,
+	"or":       tok.OR,
+	"print":    tok.PRINT,
+	"return":   tok.RETURN,
+	"select":   tok.SELECT,
+	"spawn":    tok.SPAWN,
+	"static":   tok.STATIC,
+	"super":    tok.SUPER,
+	"this":     tok.THIS,
+	"throw":    tok.THROW,
+	"trait":    tok.TRAIT,
+	"true":     tok.TRUE,
+	"try":      tok.TRY,
+	"var":      tok.VAR,
+	"while":    tok.WHILE,
+	"with":     tok.WITH,
+	"yield":    tok.YIELD,
+}
+
+// fixedLexemes holds the lexemes always spelled the same way, scanning them
+// does not allocate a new string
+var fixedLexemes = [...]string{
+	tok.LEFT_PAREN:      "(",
+	tok.RIGHT_PAREN:     ")",
+	tok.LEFT_BRACE:      "{",
+	tok.RIGHT_BRACE:     "}",
+	tok.LEFT_BRACKET:    "[",
+	tok.RIGHT_BRACKET:   "]",
+	tok.COLON:           ":",
+	tok.COMMA:           ",",
+	tok.DOT:             ".",
+	tok.MINUS:           "-",
+	tok.PLUS:            "+",
+	tok.SEMICOLON:       ";",
+	tok.SLASH:           "/",
+	tok.STAR:            "*",
+	tok.PERCENT:         "%",
+	tok.AMPERSAND:       "&",
+	tok.PIPE:            "|",
+	tok.CARET:           "^",
+	tok.TILDE:           "~",
+	tok.BANG:            "!",
+	tok.BANG_EQUAL:      "!=",
+	tok.EQUAL:           "=",
+	tok.EQUAL_EQUAL:     "==",
+	tok.FAT_ARROW:       "=>",
+	tok.GREATER:         ">",
+	tok.GREATER_EQUAL:   ">=",
+	tok.LESS:            "<",
+	tok.LESS_EQUAL:      "<=",
+	tok.STAR_STAR:       "**",
+	tok.SLASH_SLASH:     "//",
+	tok.LESS_LESS:       "<<",
+	tok.GREATER_GREATER: ">>",
+	tok.PLUS_EQUAL:      "+=",
+	tok.MINUS_EQUAL:     "-=",
+	tok.STAR_EQUAL:      "*=",
+	tok.SLASH_EQUAL:     "/=",
+	tok.PERCENT_EQUAL:   "%=",
+	tok.PLUS_PLUS:       "++",
+	tok.MINUS_MINUS:     "--",
+	tok.ELLIPSIS:        "...",
+	tok.EOF:             "",
+}
+
+type keyword struct {
+	text      string
+	tokenType tok.TokenType
+}
+
+// keywordTable groups the keywords by first letter, looking an identifier up
+// is then a few byte comparisons instead of hashing it
+var keywordTable ['z' - 'a' + 1][]keyword
+
+func init() {
+	for text, tt := range keywords {
+		fixedLexemes[tt] = text
+		keywordTable[text[0]-'a'] = append(keywordTable[text[0]-'a'], keyword{text, tt})
+	}
+}
+
+// keyword returns the keyword spelled by the current lexeme, IDENTIFIER
+// otherwise
+func (s *Scanner) keyword() tok.TokenType {
+	c := s.at(s.start)
+	if c < 'a' || c > 'z' {
+		return tok.IDENTIFIER
+	}
+	for _, kw := range keywordTable[c-'a'] {
+		if s.lexemeIs(kw.text) {
+			return kw.tokenType
+		}
+	}
+	return tok.IDENTIFIER
 }
 
+// NewScanner returns a Scanner over an in-memory source, the lexemes it
+// returns are substrings of source
 func NewScanner(source string) Scanner {
-	return Scanner{source, []tok.Token{}, 0, 0, 1, false}
+	return Scanner{
+		src:  source,
+		end:  len(source),
+		eof:  true,
+		line: 1,
+	}
 }
 
+// NewReaderScanner returns a Scanner pulling its source from r, only keeping
+// a small window of it in memory
+func NewReaderScanner(r io.Reader) Scanner {
+	return Scanner{
+		reader: r,
+		buf:    make([]byte, 0, bufferSize),
+		line:   1,
+	}
+}
+
+// NewScannerAt returns a Scanner over a part of a larger source, the part
+// starting at the given line and byte offset of it
+func NewScannerAt(source string, line, offset int) Scanner {
+	s := NewScanner(source)
+	s.line = line
+	s.dropped = offset
+	return s
+}
+
+// ScanTokens is a convenience wrapper scanning the whole source at once
 func (s *Scanner) ScanTokens() []tok.Token {
+	tokens := []tok.Token{}
+	for {
+		t := s.NextToken()
+		tokens = append(tokens, t)
+		if t.TokenType == tok.EOF {
+			return tokens
+		}
+	}
+}
+
+// NextToken scans the source up to the next token, an EOF token is returned
+// once the source is exhausted
+func (s *Scanner) NextToken() tok.Token {
+	var leading []tok.Trivia
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.found = false
 		s.scanToken()
+		if s.found {
+			t := s.token
+			s.offset = s.dropped + s.start
+			if s.KeepTrivia {
+				t.Leading = leading
+				t.Trailing = s.trailingTrivia()
+			}
+			return t
+		}
+		if s.KeepTrivia {
+			leading = appendTrivia(leading, s.trivia())
+		}
 	}
 
-	s.tokens = append(s.tokens, tok.Token{
+	s.start = s.current
+	s.offset = s.dropped + s.start
+	return tok.Token{
 		TokenType: tok.EOF,
 		Lexeme:    "",
 		Literal:   "",
 		Line:      s.line,
-	})
-	return s.tokens
+		Leading:   leading,
+	}
+}
+
+// Offset returns the byte offset in the source of the last token NextToken
+// returned
+func (s *Scanner) Offset() int {
+	return s.offset
+}
+
+// trailingTrivia scans the trivia following a token up to the end of its line
+func (s *Scanner) trailingTrivia() []tok.Trivia {
+	var trivia []tok.Trivia
+	for {
+		c := s.peek()
+		if c != ' ' && c != '\r' && c != '\t' && !s.isCommentStart(c) {
+			return trivia
+		}
+		s.start = s.current
+		s.scanToken()
+		trivia = appendTrivia(trivia, s.trivia())
+	}
+}
+
+// trivia returns what was just scanned without making a token
+func (s *Scanner) trivia() tok.Trivia {
+	text := s.lexeme()
+	kind := tok.SKIPPED
+	switch text[0] {
+	case ' ', '\r', '\t':
+		kind = tok.WHITESPACE
+	case '\n':
+		kind = tok.NEWLINE
+	case '#', '/':
+		kind = tok.COMMENT
+	}
+	return tok.Trivia{Kind: kind, Text: text}
+}
+
+// appendTrivia merges runs of whitespace or skipped source into one trivia
+func appendTrivia(trivia []tok.Trivia, t tok.Trivia) []tok.Trivia {
+	last := len(trivia) - 1
+	if last >= 0 && trivia[last].Kind == t.Kind && (t.Kind == tok.WHITESPACE || t.Kind == tok.SKIPPED) {
+		trivia[last].Text += t.Text
+		return trivia
+	}
+	return append(trivia, t)
 }
 
 func (s *Scanner) scanToken() {
-	c := string(s.advance())
+	c := s.advance()
 	switch c {
-	case "(":
+	case '(':
 		s.addToken(tok.LEFT_PAREN)
 		break
-	case ")":
+	case ')':
 		s.addToken(tok.RIGHT_PAREN)
 		break
-	case "{":
+	case '{':
 		s.addToken(tok.LEFT_BRACE)
 		break
-	case "}":
+	case '}':
 		s.addToken(tok.RIGHT_BRACE)
 		break
-	case ",":
+	case '[':
+		s.addToken(tok.LEFT_BRACKET)
+		break
+	case ']':
+		s.addToken(tok.RIGHT_BRACKET)
+		break
+	case ':':
+		s.addToken(tok.COLON)
+		break
+	case ',':
 		s.addToken(tok.COMMA)
 		break
-	case ".":
+	case '.':
+		if s.peek() == '.' && s.peekNext() == '.' {
+			s.advance()
+			s.advance()
+			s.addToken(tok.ELLIPSIS)
+			break
+		}
 		s.addToken(tok.DOT)
 		break
-	case "-":
-		s.addToken(tok.MINUS)
+	case '-':
+		m := tok.MINUS
+		if s.match('=') {
+			m = tok.MINUS_EQUAL
+		} else if s.match('-') {
+			m = tok.MINUS_MINUS
+		}
+		s.addToken(m)
 		break
-	case "+":
-		s.addToken(tok.PLUS)
+	case '+':
+		p := tok.PLUS
+		if s.match('=') {
+			p = tok.PLUS_EQUAL
+		} else if s.match('+') {
+			p = tok.PLUS_PLUS
+		}
+		s.addToken(p)
 		break
-	case ";":
+	case ';':
 		s.addToken(tok.SEMICOLON)
 		break
-	case "*":
-		s.addToken(tok.STAR)
+	case '*':
+		st := tok.STAR
+		if s.match('*') {
+			st = tok.STAR_STAR
+		} else if s.match('=') {
+			st = tok.STAR_EQUAL
+		}
+		s.addToken(st)
+		break
+	case '%':
+		pe := tok.PERCENT
+		if s.match('=') {
+			pe = tok.PERCENT_EQUAL
+		}
+		s.addToken(pe)
+		break
+	case '&':
+		s.addToken(tok.AMPERSAND)
+		break
+	case '|':
+		s.addToken(tok.PIPE)
+		break
+	case '^':
+		s.addToken(tok.CARET)
+		break
+	case '~':
+		s.addToken(tok.TILDE)
 		break
-	case "!":
+	case '!':
 		b := tok.BANG
-		if s.match("=") {
+		if s.match('=') {
 			b = tok.BANG_EQUAL
 		}
 		s.addToken(b)
 		break
-	case "=":
+	case '=':
 		e := tok.EQUAL
-		if s.match("=") {
+		if s.match('=') {
 			e = tok.EQUAL_EQUAL
+		} else if s.match('>') {
+			e = tok.FAT_ARROW
 		}
 		s.addToken(e)
 		break
-	case "<":
+	case '<':
 		l := tok.LESS
-		if s.match("=") {
+		if s.match('=') {
 			l = tok.LESS_EQUAL
+		} else if s.match('<') {
+			l = tok.LESS_LESS
 		}
 		s.addToken(l)
 		break
-	case ">":
+	case '>':
 		g := tok.GREATER
-		if s.match("=") {
+		if s.match('=') {
 			g = tok.GREATER_EQUAL
+		} else if s.match('>') {
+			g = tok.GREATER_GREATER
 		}
 		s.addToken(g)
 		break
-	case "/":
-		if s.match("/") {
-			for s.peek() != "\n" && !s.isAtEnd() {
-				s.advance()
-			}
+	case '#':
+		s.comment()
+		break
+	case '/':
+		if s.SlashComments && s.match('/') {
+			s.comment()
+		} else if s.match('/') {
+			s.addToken(tok.SLASH_SLASH)
+		} else if s.match('=') {
+			s.addToken(tok.SLASH_EQUAL)
 		} else {
 			s.addToken(tok.SLASH)
 		}
 		break
-	case " ":
-	case "\r":
-	case "\t":
+	case ' ':
+	case '\r':
+	case '\t':
 		// Ignore
 		break
-	case "\n":
+	case '\n':
 		s.line++
 		break
-	case "\"":
+	case '"':
 		s.string()
 		break
 	default:
@@ -140,6 +424,8 @@ func (s *Scanner) scanToken() {
 			s.number()
 		} else if s.isAlpha(c) {
 			s.identifier()
+		} else if c >= utf8.RuneSelf {
+			s.unicode()
 		} else {
 			s.Error(s.line, "Unexpected character.")
 		}
@@ -147,53 +433,72 @@ func (s *Scanner) scanToken() {
 	}
 }
 
+func (s *Scanner) comment() {
+	for s.peek() != '\n' && !s.isAtEnd() {
+		s.advance()
+		// Comments are dropped, they need not stay in the window
+		if !s.KeepTrivia {
+			s.start = s.current
+		}
+	}
+}
+
+// isCommentStart tells whether c, the next byte, starts a comment
+func (s *Scanner) isCommentStart(c byte) bool {
+	return c == '#' || (s.SlashComments && c == '/' && s.peekNext() == '/')
+}
+
 func (s *Scanner) addToken(tokenType tok.TokenType, literals ...interface{}) {
-	text := s.source[s.start:s.current]
+	text := fixedLexemes[tokenType]
+	if text == "" {
+		text = s.lexeme()
+	}
 	var literal interface{}
 	if literals != nil {
 		literal = literals[0]
 	}
-	s.tokens = append(s.tokens, tok.Token{
+	s.token = tok.Token{
 		TokenType: tokenType,
 		Lexeme:    text,
 		Literal:   literal,
 		Line:      s.line,
-	})
+	}
+	s.found = true
 }
 
 func (s *Scanner) advance() byte {
 	s.current++
-	return s.source[s.current-1]
+	return s.at(s.current - 1)
 }
 
-func (s *Scanner) match(expected string) bool {
+func (s *Scanner) match(expected byte) bool {
 	if s.isAtEnd() {
 		return false
 	}
-	if s.source[s.current] != expected[0] {
+	if s.at(s.current) != expected {
 		return false
 	}
 	s.current++
 	return true
 }
 
-func (s *Scanner) peek() string {
-	if s.isAtEnd() {
-		return "\000"
+func (s *Scanner) peek() byte {
+	if s.current >= s.end && !s.fill(1) {
+		return 0
 	}
-	return string(s.source[s.current])
+	return s.at(s.current)
 }
 
-func (s *Scanner) peekNext() string {
-	if s.current+1 >= len(s.source) {
-		return "\000"
+func (s *Scanner) peekNext() byte {
+	if s.current+1 >= s.end && !s.fill(2) {
+		return 0
 	}
-	return string(s.source[s.current+1])
+	return s.at(s.current + 1)
 }
 
 func (s *Scanner) string() {
-	for s.peek() != "\"" && !s.isAtEnd() {
-		if s.peek() == "\n" {
+	for s.peek() != '"' && !s.isAtEnd() {
+		if s.peek() == '\n' {
 			s.line++
 		}
 		s.advance()
@@ -208,9 +513,9 @@ func (s *Scanner) string() {
 	// We are at closing "
 	s.advance()
 
-	// Get the whole string at once
-	str := s.source[s.start+1 : s.current-1]
-	s.addToken(tok.STRING, str)
+	// Get the whole string at once, the literal shares the lexeme without quotes
+	s.addToken(tok.STRING)
+	s.token.Literal = s.token.Lexeme[1 : len(s.token.Lexeme)-1]
 }
 
 func (s *Scanner) number() {
@@ -219,49 +524,136 @@ func (s *Scanner) number() {
 	}
 
 	// . ?
-	if s.peek() == "." && s.isDigit(s.peekNext()) {
+	if s.peek() == '.' && s.isDigit(s.peekNext()) {
 		s.advance()
 		for s.isDigit(s.peek()) {
 			s.advance()
 		}
 	}
 
-	num, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	// Parse the lexeme instead of allocating another string
+	s.addToken(tok.NUMBER)
+	num, err := strconv.ParseFloat(s.token.Lexeme, 64)
 	if err != nil {
+		s.found = false
 		s.Error(s.line, "Could not convert to number.")
 		return
 	}
-	s.addToken(tok.NUMBER, num)
+	s.token.Literal = num
+}
+
+// unicode skips a character outside of ASCII, they are not part of the language
+func (s *Scanner) unicode() {
+	s.fill(utf8.UTFMax - 1)
+	var r rune
+	var size int
+	if s.reader == nil {
+		r, size = utf8.DecodeRuneInString(s.src[s.start:])
+	} else {
+		r, size = utf8.DecodeRune(s.buf[s.start:])
+	}
+	if r == utf8.RuneError && size == 1 {
+		s.Error(s.line, "Invalid UTF-8 encoding.")
+		return
+	}
+	s.current = s.start + size
+	s.Error(s.line, "Unexpected character.")
 }
 
 func (s *Scanner) identifier() {
 	for s.isAlphaNumeric(s.peek()) {
 		s.advance()
 	}
-	txt := s.source[s.start:s.current]
-	tt, found := keywords[txt]
-	if !found {
-		tt = tok.IDENTIFIER
-	}
-	s.addToken(tt)
+	s.addToken(s.keyword())
 }
 
-func (s Scanner) isDigit(c string) bool {
-	return c >= "0" && c <= "9"
+func (s Scanner) isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
 
-func (s Scanner) isAlpha(c string) bool {
-	return (c >= "a" && c <= "z") ||
-		(c >= "A" && c <= "Z") ||
-		c == "_"
+func (s Scanner) isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		c == '_'
 }
 
-func (s Scanner) isAlphaNumeric(c string) bool {
+func (s Scanner) isAlphaNumeric(c byte) bool {
 	return s.isAlpha(c) || s.isDigit(c)
 }
 
-func (s Scanner) isAtEnd() bool {
-	return s.current >= len(s.source)
+func (s *Scanner) isAtEnd() bool {
+	return s.current >= s.end && !s.fill(1)
+}
+
+// at returns the byte at i in the source or its window
+func (s *Scanner) at(i int) byte {
+	if s.reader == nil {
+		return s.src[i]
+	}
+	return s.buf[i]
+}
+
+// lexeme returns what was scanned since start, only a reader's window has to
+// be copied
+func (s *Scanner) lexeme() string {
+	if s.reader == nil {
+		return s.src[s.start:s.current]
+	}
+	return string(s.buf[s.start:s.current])
+}
+
+// lexemeIs tells whether what was scanned since start is text, without
+// copying it
+func (s *Scanner) lexemeIs(text string) bool {
+	if s.reader == nil {
+		return s.src[s.start:s.current] == text
+	}
+	return string(s.buf[s.start:s.current]) == text
+}
+
+// fill makes sure n bytes past current are in the window, reading more of the
+// source if needed, and reports whether there are. A string source is always
+// whole, there is nothing more to read.
+func (s *Scanner) fill(n int) bool {
+	emptyReads := 0
+	for s.current+n > s.end {
+		if s.eof {
+			return false
+		}
+
+		// Drop what was scanned before the current token
+		if s.start > 0 {
+			kept := copy(s.buf, s.buf[s.start:])
+			s.buf = s.buf[:kept]
+			s.dropped += s.start
+			s.current -= s.start
+			s.start = 0
+		}
+
+		// Only grow for tokens longer than the window
+		if len(s.buf) == cap(s.buf) {
+			grown := make([]byte, len(s.buf), 2*cap(s.buf)+bufferSize)
+			copy(grown, s.buf)
+			s.buf = grown
+		}
+
+		read, err := s.reader.Read(s.buf[len(s.buf):cap(s.buf)])
+		s.buf = s.buf[:len(s.buf)+read]
+		s.end = len(s.buf)
+		if read == 0 && err == nil {
+			emptyReads++
+			if emptyReads == maxEmptyReads {
+				err = io.ErrNoProgress
+			}
+		}
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			s.eof = true
+			s.Error(s.line, err.Error())
+		}
+	}
+	return true
 }
 
 // Error spits out failures in the program
@@ -282,3 +674,8 @@ func Error(t tok.Token, msg string) {
 		s.Report(t.Line, "at '"+t.Lexeme+"'", msg)
 	}
 }
+
+// Warn points out a likely mistake that is not an error
+func Warn(t tok.Token, msg string) {
+	fmt.Printf("[line \"%d\"] Warning at '%s': %s\n", t.Line, t.Lexeme, msg)
+}
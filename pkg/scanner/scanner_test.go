@@ -0,0 +1,193 @@
+package scanner
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	tok "github.com/cedricmar/bazic/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderScanner(t *testing.T) {
+	// The string is longer than the window so it has to grow
+	long := strings.Repeat("a", 3*bufferSize)
+	src := "## comment\n(1.5 + \"" + long + "\") >= foo\n!= nil"
+
+	sc := NewScanner(src)
+	want := sc.ScanTokens()
+
+	rsc := NewReaderScanner(iotest.OneByteReader(strings.NewReader(src)))
+	var got []tok.Token
+	var offsets []int
+	for {
+		t := rsc.NextToken()
+		got = append(got, t)
+		offsets = append(offsets, rsc.Offset())
+		if t.TokenType == tok.EOF {
+			break
+		}
+	}
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, 10, len(got))
+	assert.Equal(t, long, got[3].Literal)
+	assert.Equal(t, 3, got[9].Line)
+	assert.Equal(t, []int{11, 12, 16, 18, 20 + len(long), 22 + len(long), 25 + len(long), 29 + len(long), 32 + len(long), len(src)}, offsets)
+	assert.LessOrEqual(t, cap(rsc.buf), 3*len(long))
+}
+
+func TestStringScannerSharesSource(t *testing.T) {
+	src := "var name = other_name and (this) # done"
+
+	// Lexemes are slices of the source, not copies
+	allocs := testing.AllocsPerRun(10, func() {
+		sc := NewScanner(src)
+		for sc.NextToken().TokenType != tok.EOF {
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	sc := NewScanner(src)
+	tokens := sc.ScanTokens()
+	assert.Equal(t, "name", tokens[1].Lexeme)
+	assert.Equal(t, "other_name", tokens[3].Lexeme)
+	assert.Equal(t, tok.EOF, tokens[8].TokenType)
+}
+
+func TestKeywords(t *testing.T) {
+	for text, tt := range keywords {
+		sc := NewScanner(text + " " + text + "s _" + text)
+		tokens := sc.ScanTokens()
+
+		assert.Equal(t, tt, tokens[0].TokenType)
+		assert.Equal(t, text, tokens[0].Lexeme)
+		assert.Equal(t, tok.IDENTIFIER, tokens[1].TokenType)
+		assert.Equal(t, tok.IDENTIFIER, tokens[2].TokenType)
+	}
+}
+
+func TestComments(t *testing.T) {
+	sc := NewScanner("# comment\n1 // 2")
+	tokens := sc.ScanTokens()
+	assert.Equal(t, 4, len(tokens))
+	assert.Equal(t, tok.SLASH_SLASH, tokens[1].TokenType)
+
+	sc = NewScanner("# comment\n1 // 2")
+	sc.SlashComments = true
+	tokens = sc.ScanTokens()
+	assert.Equal(t, 2, len(tokens))
+	assert.Equal(t, tok.NUMBER, tokens[0].TokenType)
+}
+
+func TestNonASCII(t *testing.T) {
+	sc := NewScanner("\xff1 é \"é\"")
+	tokens := sc.ScanTokens()
+
+	assert.True(t, sc.HadError)
+	assert.Equal(t, 3, len(tokens))
+	assert.Equal(t, 1.0, tokens[0].Literal)
+	assert.Equal(t, "é", tokens[1].Literal)
+}
+
+func FuzzScanTokens(f *testing.F) {
+	seeds := []string{
+		"",
+		"(1.5 + -count) * 3 >= \"total\" != !false and nil # done\n",
+		"1 // 2 ** 3 << 4 >> 5 % 6 & 7 | 8 ^ ~9",
+		"var while return class this super fun for if else or and print true",
+		"\"unterminated\n",
+		"12.",
+		"1.2.3",
+		"@#$",
+		"\xff\xfe",
+		"é # ü\r\n\t",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		sc := NewScanner(src)
+		tokens := sc.ScanTokens()
+		if tokens[len(tokens)-1].TokenType != tok.EOF {
+			t.Fatalf("tokens do not end with EOF")
+		}
+
+		// Streaming scans the same
+		rsc := NewReaderScanner(iotest.OneByteReader(strings.NewReader(src)))
+		assert.Equal(t, tokens, rsc.ScanTokens())
+
+		// With its trivia, every byte of the source is kept
+		tsc := NewScanner(src)
+		tsc.KeepTrivia = true
+		var buf strings.Builder
+		for _, t := range tsc.ScanTokens() {
+			for _, tr := range t.Leading {
+				buf.WriteString(tr.Text)
+			}
+			buf.WriteString(t.Lexeme)
+			for _, tr := range t.Trailing {
+				buf.WriteString(tr.Text)
+			}
+		}
+		assert.Equal(t, src, buf.String())
+	})
+}
+
+// benchSource repeats snippet until it is at least 4MB long
+func benchSource(snippet string) string {
+	return strings.Repeat(snippet, 4<<20/len(snippet)+1)
+}
+
+func benchmarkScan(b *testing.B, src string) {
+	var count int
+	var before, after runtime.MemStats
+
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sc := NewScanner(src)
+		count = 0
+		for sc.NextToken().TokenType != tok.EOF {
+			count++
+		}
+	}
+
+	b.StopTimer()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	if count == 0 {
+		return
+	}
+	total := float64(count) * float64(b.N)
+	b.ReportMetric(total/elapsed.Seconds(), "tokens/s")
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/total, "allocs/token")
+}
+
+func BenchmarkScanMixed(b *testing.B) {
+	benchmarkScan(b, benchSource("(12.5 + -count) * 3 >= \"total\" != !false and nil # done\n"))
+}
+
+func BenchmarkScanKeywords(b *testing.B) {
+	benchmarkScan(b, benchSource("var while return class this super fun for if else or and print true\n"))
+}
+
+func BenchmarkScanIdentifiers(b *testing.B) {
+	benchmarkScan(b, benchSource("alpha beta_2 gamma_delta epsilon zeta eta theta iota kappa\n"))
+}
+
+func BenchmarkScanNumbers(b *testing.B) {
+	benchmarkScan(b, benchSource("1 23 456.7 8.9 1000 0.5 42 3.14159\n"))
+}
+
+func BenchmarkScanComments(b *testing.B) {
+	benchmarkScan(b, benchSource("# nothing to see here, only a comment to skip over\n"))
+}
@@ -7,10 +7,13 @@ type Token struct {
 	Lexeme    string
 	Literal   interface{}
 	Line      int
+	// Only filled when the scanner keeps trivia
+	Leading  []Trivia
+	Trailing []Trivia
 }
 
 func NewToken(tokenType TokenType, lexeme string, literal interface{}, line int) Token {
-	return Token{tokenType, lexeme, literal, line}
+	return Token{tokenType, lexeme, literal, line, nil, nil}
 }
 
 func (tok Token) toString() string {
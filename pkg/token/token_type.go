@@ -8,6 +8,9 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
+	COLON
 	COMMA
 	DOT
 	MINUS
@@ -15,16 +18,36 @@ const (
 	SEMICOLON
 	SLASH
 	STAR
+	PERCENT
+	AMPERSAND
+	PIPE
+	CARET
+	TILDE
 
 	// One or two character tokens.
 	BANG
 	BANG_EQUAL
 	EQUAL
 	EQUAL_EQUAL
+	FAT_ARROW
 	GREATER
 	GREATER_EQUAL
 	LESS
 	LESS_EQUAL
+	STAR_STAR
+	SLASH_SLASH
+	LESS_LESS
+	GREATER_GREATER
+	PLUS_EQUAL
+	MINUS_EQUAL
+	STAR_EQUAL
+	SLASH_EQUAL
+	PERCENT_EQUAL
+	PLUS_PLUS
+	MINUS_MINUS
+
+	// Three character tokens.
+	ELLIPSIS
 
 	// Literals.
 	IDENTIFIER
@@ -33,21 +56,43 @@ const (
 
 	// Keywords.
 	AND
+	AS
+	ASYNC
+	AWAIT
+	BREAK
+	CATCH
 	CLASS
+	CONST
+	CONTINUE
 	ELSE
+	ENUM
+	EXPORT
 	FALSE
+	FINALLY
+	FROM
 	FUN
 	FOR
 	IF
+	IMPORT
+	IN
+	MATCH
 	NIL
 	OR
 	PRINT
 	RETURN
+	SELECT
+	SPAWN
+	STATIC
 	SUPER
 	THIS
+	THROW
+	TRAIT
 	TRUE
+	TRY
 	VAR
 	WHILE
+	WITH
+	YIELD
 
 	EOF
 )
@@ -0,0 +1,18 @@
+package token
+
+type TriviaKind int
+
+const (
+	WHITESPACE TriviaKind = iota
+	NEWLINE
+	COMMENT
+	// Source the scanner could not make a token of
+	SKIPPED
+)
+
+// Trivia is a piece of source with no meaning for the program, it is only
+// kept so that tools can print the source back
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}